@@ -2,6 +2,8 @@ package auth_manager
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,33 +11,265 @@ import (
 
 var TokenEncodingAlgorithm = jwt.SigningMethodHS512
 
+const jtiByteLength = 16
+
+// AccessTokenClaims are the claims of an access token. The registered jwt claims are mapped to plain fields,
+// so the API doesn't change with the version of the jwt library the tokens are signed and parsed with.
 type AccessTokenClaims struct {
 	Payload TokenPayload
+
+	// SessionID is the session the token was issued for by GenerateSessionAccessToken.
+	SessionID string
+
+	// ID is the jti claim, the id RevokeByJTI takes.
+	ID        string
+	Subject   string
+	Issuer    string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// accessTokenClaims is the jwt form of AccessTokenClaims, the only place the jwt library's claims appear.
+type accessTokenClaims struct {
+	Payload   TokenPayload
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+func (c AccessTokenClaims) jwtClaims() accessTokenClaims {
+	claims := accessTokenClaims{
+		Payload:   c.Payload,
+		SessionID: c.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       c.ID,
+			Subject:  c.Subject,
+			Issuer:   c.Issuer,
+			Audience: c.Audience,
+		},
+	}
+	if !c.IssuedAt.IsZero() {
+		claims.IssuedAt = jwt.NewNumericDate(c.IssuedAt)
+	}
+	if !c.ExpiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(c.ExpiresAt)
+	}
+
+	return claims
+}
+
+func (c *accessTokenClaims) claims() *AccessTokenClaims {
+	claims := &AccessTokenClaims{
+		Payload:   c.Payload,
+		SessionID: c.SessionID,
+		ID:        c.ID,
+		Subject:   c.Subject,
+		Issuer:    c.Issuer,
+		Audience:  c.Audience,
+	}
+	if c.RegisteredClaims.IssuedAt != nil {
+		claims.IssuedAt = c.RegisteredClaims.IssuedAt.Time
+	}
+	if c.RegisteredClaims.ExpiresAt != nil {
+		claims.ExpiresAt = c.RegisteredClaims.ExpiresAt.Time
+	}
+
+	return claims
+}
+
 // The GenerateAccessToken method is used to generate Stateless JWT Token.
 // Notice that access tokens are not store at Redis Store and they are stateless!
+// The uuid is also set as the standard `sub` claim so gateways and other jwt consumers can read it.
 func (t *authManager) GenerateAccessToken(ctx context.Context, uuid string, expiresAt time.Duration) (string, error) {
-	now := time.Now()
+	uuid, err := t.opts.validateUUID(uuid)
+	if err != nil {
+		return "", err
+	}
 
-	claims := AccessTokenClaims{
-		Payload: TokenPayload{
-			UUID:      uuid,
-			TokenType: AccessToken,
-			CreatedAt: time.Now(),
-		},
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(expiresAt)),
-			Issuer:    "go-auth-manager",
-		},
+	expiresAt, err = t.clampExpiry(ctx, uuid, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	token, jti, err := t.generateAccessToken(ctx, uuid, "", expiresAt)
+	if err != nil {
+		return "", err
 	}
-	jwtToken, err := jwt.NewWithClaims(TokenEncodingAlgorithm, claims).SignedString([]byte(t.opts.PrivateKey))
+
+	err = t.recordAccessToken(ctx, uuid, jti, expiresAt)
 	if err != nil {
 		return "", err
 	}
 
-	return jwtToken, nil
+	return token, nil
+}
+
+// generateAccessToken signs a new access token, for the session when sessionID is set, and also returns its jti.
+// The uuid must have passed validateUUID already.
+func (t *authManager) generateAccessToken(ctx context.Context, uuid string, sessionID string, expiresAt time.Duration) (string, string, error) {
+	if err := t.opts.checkAllowedTokenType(AccessToken); err != nil {
+		return "", "", err
+	}
+	if err := t.validateOpts(); err != nil {
+		return "", "", err
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", "", err
+	}
+
+	epoch, err := t.userEpoch(ctx, uuid)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+
+	jti, err := generateRandomString(jtiByteLength)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := t.newAccessTokenClaims(TokenPayload{
+		UUID:      uuid,
+		TokenType: AccessToken,
+		CreatedAt: now,
+		Epoch:     epoch,
+	}, jti, now, now.Add(expiresAt))
+	claims.SessionID = sessionID
+
+	if err := claims.Validate(); err != nil {
+		return "", "", err
+	}
+
+	jwtToken, err := t.signToken(AccessToken, claims.jwtClaims())
+	if err != nil {
+		return "", "", err
+	}
+
+	return jwtToken, jti, nil
+}
+
+// Validate checks the payload like TokenPayload.Validate and that the registered claims agree with it: the
+// token type is AccessToken, the Subject is the uuid of the payload and the token expires after it was issued.
+func (c *AccessTokenClaims) Validate() error {
+	if err := c.Payload.Validate(); err != nil {
+		return err
+	}
+	if c.Payload.TokenType != AccessToken {
+		return ErrInvalidTokenType
+	}
+	if c.Subject != c.Payload.UUID {
+		return ErrSubjectMismatch
+	}
+	if c.ExpiresAt.IsZero() {
+		return ErrNoExpiration
+	}
+	if !c.ExpiresAt.After(issuedAt(c)) {
+		return ErrInvalidExpiry
+	}
+
+	return nil
+}
+
+// newAccessTokenClaims returns the access token claims for the payload.
+func (t *authManager) newAccessTokenClaims(payload TokenPayload, jti string, issuedAt time.Time, expiresAt time.Time) AccessTokenClaims {
+	return AccessTokenClaims{
+		Payload:   payload,
+		ID:        jti,
+		Subject:   payload.UUID,
+		Audience:  t.opts.Audience,
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+		Issuer:    "go-auth-manager",
+	}
+}
+
+// beyondExpiryHorizon reports whether the exp of a jwt is further in the future than MaxExpiryHorizon allows.
+func (t *authManager) beyondExpiryHorizon(expiresAt time.Time) bool {
+	return t.opts.MaxExpiryHorizon > 0 && time.Until(expiresAt) > t.opts.MaxExpiryHorizon
+}
+
+// isJWT reports whether the token has the three dot separated segments of a jwt.
+// Plain and refresh tokens are raw base64 strings and never contain a dot.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// issuedAt returns the iat claim of the token, falling back to the payload's CreatedAt
+// for tokens generated before iat was set.
+func issuedAt(claims *AccessTokenClaims) time.Time {
+	if !claims.IssuedAt.IsZero() {
+		return claims.IssuedAt
+	}
+
+	return claims.Payload.CreatedAt
+}
+
+// parseWithClaims parses and verifies the token with the parser of the manager. Decode paths check validJWTFormat
+// first, but a panic of the jwt library, or of a Signer, on adversarial input is still turned into ErrInvalidToken
+// so it can't take down the request handler. Every jwt decode path must use it.
+func (t *authManager) parseWithClaims(token string, claims jwt.Claims, keyFunc jwt.Keyfunc) (jwtToken *jwt.Token, err error) {
+	defer func() {
+		if recover() != nil {
+			jwtToken, err = nil, ErrInvalidToken
+		}
+	}()
+
+	return t.parser.ParseWithClaims(token, claims, keyFunc)
+}
+
+// parseError maps an error of the jwt parser to the errors of this package,
+// keeping expiration distinguishable from any other validation failure.
+func parseError(err error) error {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return ErrTokenExpired
+	}
+	if errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+		return ErrNoExpiration
+	}
+
+	return ErrInvalidToken
+}
+
+// keyFunc is the single place where the signing method of an incoming token is checked
+// and the verification key is looked up. Every jwt decode path must use it.
+func (t *authManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	// Only the method configured for the claimed type is accepted, which the decode methods then check.
+	if signer := t.signerFor(claimsTokenType(token.Claims)); signer != nil {
+		if token.Method.Alg() != signer.Alg() {
+			return nil, ErrUnexpectedSigningMethod
+		}
+
+		// The parser verifies with token.Method once the key is returned, route it through the Signer.
+		token.Method = signerMethod{signer}
+		return nil, nil
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, ErrUnexpectedSigningMethod
+	}
+
+	t.keysMu.RLock()
+	defer t.keysMu.RUnlock()
+
+	return t.verificationKey, nil
+}
+
+// newVerificationKey returns the key handed to the jwt parser: the signing secret alone,
+// or a key set of the signing secret followed by the VerificationKeys.
+func newVerificationKey(opts AuthManagerOpts) interface{} {
+	if len(opts.VerificationKeys) == 0 {
+		return opts.secret(opts.signingSecret())
+	}
+
+	keySet := jwt.VerificationKeySet{
+		Keys: []jwt.VerificationKey{opts.secret(opts.signingSecret())},
+	}
+	for _, key := range opts.VerificationKeys {
+		keySet.Keys = append(keySet.Keys, opts.secret(key))
+	}
+
+	return keySet
 }
 
 // DecodeAccessToken parses and validates an access token (JWT) and returns its claims.
@@ -43,6 +277,14 @@ func (t *authManager) GenerateAccessToken(ctx context.Context, uuid string, expi
 // 1. Verifies the token signature using the provided private key.
 // 2. Checks the token's expiration time to ensure it is still valid.
 // 3. Validates that the token type is specifically an AccessToken.
+// 4. Rejects tokens older than MaxTokenAge when it is set.
+// 5. Rejects tokens whose jti has been revoked with RevokeByJTI.
+// 6. With VerifySession, rejects tokens whose session is no longer alive.
+// 7. With UserEpochs, rejects tokens issued before the last BumpUserEpoch of the user.
+// 8. With SingleSession, rejects tokens which are not the user's most recently issued one.
+// 9. Runs the ClaimsValidator when it is set.
+//
+// The age of a valid token is reported to the Metrics when they are set.
 //
 // If any of these checks fail, an appropriate error is returned.
 // If the token is valid, the function returns the decoded AccessTokenClaims.
@@ -55,20 +297,43 @@ func (t *authManager) GenerateAccessToken(ctx context.Context, uuid string, expi
 //   - *AccessTokenClaims: The claims embedded in the token, if valid.
 //   - error: Any error encountered during decoding or validation (e.g., invalid token, expired token).
 func (t *authManager) DecodeAccessToken(ctx context.Context, token string) (*AccessTokenClaims, error) {
-	claims := &AccessTokenClaims{}
-	jwtToken, err := jwt.ParseWithClaims(token, claims,
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, ErrUnexpectedSigningMethod
-			}
+	claims, err := t.decodeAccessToken(ctx, trimToken(token))
+	if err != nil {
+		return nil, t.disclose(err)
+	}
 
-			return []byte(t.opts.PrivateKey), nil
-		},
-	)
+	err = t.validateClaims(ctx, &claims.Payload)
 	if err != nil {
+		return nil, t.disclose(err)
+	}
+
+	t.observeTokenAge(&claims.Payload)
+
+	return claims, nil
+}
+
+func (t *authManager) decodeAccessToken(ctx context.Context, token string) (*AccessTokenClaims, error) {
+	if err := t.checkTokenSize(token); err != nil {
+		return nil, err
+	}
+	if !validJWTFormat(token) {
 		return nil, ErrInvalidToken
 	}
 
+	if cached, ok := t.cache.get(token); ok {
+		if cachedClaims, ok := cached.(AccessTokenClaims); ok {
+			return &cachedClaims, nil
+		}
+	}
+	cacheVersion := t.cache.version()
+
+	parsedClaims := &accessTokenClaims{}
+	jwtToken, err := t.parseWithClaims(token, parsedClaims, t.keyFunc)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	claims := parsedClaims.claims()
+
 	expr, err := jwtToken.Claims.GetExpirationTime()
 	if err != nil || expr == nil {
 		return nil, ErrNoExpiration
@@ -79,12 +344,79 @@ func (t *authManager) DecodeAccessToken(ctx context.Context, token string) (*Acc
 	if expr.Time.Before(now) {
 		return nil, ErrTokenExpired
 	}
+	if t.beyondExpiryHorizon(expr.Time) {
+		return nil, ErrInvalidToken
+	}
 
 	if jwtToken.Valid {
 		if claims.Payload.TokenType != AccessToken {
 			return nil, ErrInvalidTokenType
 		}
 
+		if t.opts.MaxTokenAge > 0 && issuedAt(claims).Add(t.opts.MaxTokenAge).Before(now) {
+			return nil, ErrTokenTooOld
+		}
+
+		// A result accepted without reaching Redis is not cached, so it is checked again once Redis is back.
+		failedOpen := false
+
+		if claims.ID != "" {
+			revoked, err := t.isRevoked(ctx, claims.ID)
+			if err != nil && !t.failOpen() {
+				return nil, err
+			}
+			failedOpen = failedOpen || err != nil
+			if revoked {
+				return nil, ErrTokenRevoked
+			}
+		}
+
+		if t.opts.VerifySession {
+			alive, err := t.sessionAlive(ctx, claims.SessionID)
+			if err != nil && !t.failOpen() {
+				return nil, err
+			}
+			failedOpen = failedOpen || err != nil
+			if !alive && err == nil {
+				return nil, ErrSessionExpired
+			}
+		}
+
+		if t.opts.UserEpochs {
+			epoch, err := t.userEpoch(ctx, claims.Payload.UUID)
+			if err != nil && !t.failOpen() {
+				return nil, err
+			}
+			failedOpen = failedOpen || err != nil
+			if epoch != claims.Payload.Epoch && err == nil {
+				return nil, ErrEpochMismatch
+			}
+		}
+
+		if t.opts.SingleSession {
+			current, err := t.isCurrentToken(ctx, claims.Payload.UUID, claims.ID)
+			if err != nil && !t.failOpen() {
+				return nil, err
+			}
+			failedOpen = failedOpen || err != nil
+			if !current && err == nil {
+				return nil, ErrSessionSuperseded
+			}
+		}
+
+		if failedOpen {
+			return claims, nil
+		}
+
+		validUntil := expr.Time
+		if t.opts.MaxTokenAge > 0 {
+			maxAge := issuedAt(claims).Add(t.opts.MaxTokenAge)
+			if maxAge.Before(validUntil) {
+				validUntil = maxAge
+			}
+		}
+		t.cache.set(token, claims.Payload.UUID, *claims, validUntil, cacheVersion)
+
 		return claims, nil
 	}
 
@@ -0,0 +1,92 @@
+package auth_manager
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// EnumerateActiveUsers returns the uuids which currently have at least one plain or refresh token.
+//
+// Tokens which expire are not removed from their owner's index by Redis itself, so the set is
+// only eventually consistent. To compensate, every listed user's index is reconciled against the
+// live tokens before it is returned, which makes this an administrative rather than a hot-path call.
+func (t *authManager) EnumerateActiveUsers(ctx context.Context) ([]string, error) {
+	uuids, err := t.redisClient.SMembers(ctx, t.keys.activeUsersKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	activeUsers := make([]string, 0, len(uuids))
+	for _, uuid := range uuids {
+		err = t.cleanupUserIndex(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+
+		active, err := t.pruneActiveUser(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if active {
+			activeUsers = append(activeUsers, uuid)
+		}
+	}
+
+	return activeUsers, nil
+}
+
+// cleanupUserIndex removes the plain tokens which have already expired from the user's index.
+func (t *authManager) cleanupUserIndex(ctx context.Context, uuid string) error {
+	tokens, err := t.redisClient.SMembers(ctx, t.keys.generateIndexKey(uuid)).Result()
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	existsCmds := make([]*redis.IntCmd, len(tokens))
+	_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, token := range tokens {
+			existsCmds[i] = pipe.Exists(ctx, t.keys.generatePlainTokenKey(token))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	expired := []interface{}{}
+	for i, cmd := range existsCmds {
+		if cmd.Val() == 0 {
+			expired = append(expired, tokens[i])
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	return t.redisClient.SRem(ctx, t.keys.generateIndexKey(uuid), expired...).Err()
+}
+
+// pruneActiveUserScript removes ARGV[1] from the active users, KEYS[3], unless the user still has plain tokens in
+// the index, KEYS[1], or refresh tokens, KEYS[2]. The check and the removal are one step, so a token issued in
+// between keeps the user active.
+var pruneActiveUserScript = redis.NewScript(`
+if redis.call('SCARD', KEYS[1]) > 0 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 1
+end
+
+redis.call('SREM', KEYS[3], ARGV[1])
+return 0
+`)
+
+// pruneActiveUser removes the user from the active users once they have no plain or refresh token left,
+// and reports whether the user is still active.
+func (t *authManager) pruneActiveUser(ctx context.Context, uuid string) (bool, error) {
+	keys := []string{t.keys.generateIndexKey(uuid), t.keys.generateHashKey(uuid), t.keys.activeUsersKey()}
+	active, err := pruneActiveUserScript.Run(ctx, t.redisClient, keys, uuid).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return active == 1, nil
+}
@@ -0,0 +1,96 @@
+package auth_manager_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_EnumerateActiveUsers() {
+	ctx := context.TODO()
+	plainUser, refreshUser, destroyedUser, expiredUser := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+	// The active users of the other tests would slow the walk down past the expiration of expiredUser's token
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  uuid.NewString(),
+	})
+
+	generate := func(userUUID string, expiresAt time.Duration) string {
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			TokenType: auth_manager.VerifyEmail,
+			CreatedAt: time.Now(),
+		}, expiresAt)
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	generate(plainUser, time.Minute)
+	destroyedToken := generate(destroyedUser, time.Minute)
+	generate(expiredUser, time.Millisecond*200)
+
+	_, err := manager.GenerateRefreshToken(ctx, refreshUser, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	activeUsers, err := manager.EnumerateActiveUsers(ctx)
+	require.NoError(s.T(), err)
+	require.Subset(s.T(), activeUsers, []string{plainUser, refreshUser, destroyedUser, expiredUser})
+
+	err = manager.DestroyPlainToken(ctx, destroyedToken)
+	require.NoError(s.T(), err)
+
+	time.Sleep(time.Millisecond * 400)
+
+	activeUsers, err = manager.EnumerateActiveUsers(ctx)
+	require.NoError(s.T(), err)
+	require.Subset(s.T(), activeUsers, []string{plainUser, refreshUser})
+	require.NotContains(s.T(), activeUsers, destroyedUser)
+	require.NotContains(s.T(), activeUsers, expiredUser)
+
+	err = manager.TerminateRefreshTokens(ctx, refreshUser)
+	require.NoError(s.T(), err)
+
+	activeUsers, err = manager.EnumerateActiveUsers(ctx)
+	require.NoError(s.T(), err)
+	require.NotContains(s.T(), activeUsers, refreshUser)
+}
+
+func (s *AuthManagerTestSuite) Test_PruneActiveUserRacingGenerate() {
+	ctx := context.TODO()
+
+	generate := func(userUUID string) string {
+		token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	// Destroying the last token of a user while another one is issued never leaves the user inactive
+	for i := 0; i < 50; i++ {
+		userUUID := uuid.NewString()
+		token := generate(userUUID)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			require.NoError(s.T(), s.authManager.DestroyPlainToken(ctx, token))
+		}()
+		go func() {
+			defer wg.Done()
+			generate(userUUID)
+		}()
+		wg.Wait()
+
+		isMember, err := redisClient.SIsMember(ctx, "active_users", userUUID).Result()
+		require.NoError(s.T(), err)
+		require.True(s.T(), isMember)
+	}
+}
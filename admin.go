@@ -0,0 +1,266 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scanBatchSize is the COUNT hint passed to SCAN and so the maximum number of keys handled per round-trip.
+const scanBatchSize = 100
+
+// DestroyByPattern removes every plain token and refresh token hash whose key matches the glob-style pattern and
+// returns the number of deleted keys. The pattern is matched against the keys without the KeyPrefix, which is
+// added to it, so "*" matches every token of this manager and nothing of other applications sharing Redis.
+// Revocations, epochs, sessions, tombstones and any other key are never deleted, since dropping them would bring
+// revoked tokens back. The keyspace is walked with SCAN and each batch is deleted in a single pipeline, so it is
+// safe to run against large keyspaces. Plain tokens are also removed from their owner's index.
+func (t *authManager) DestroyByPattern(ctx context.Context, pattern string) (int, error) {
+	deleted := 0
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.prefix+pattern, scanBatchSize).Result()
+		if err != nil {
+			return deleted, err
+		}
+
+		tokenKeys := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if _, ok := t.keys.plainTokenFromKey(key); ok {
+				tokenKeys = append(tokenKeys, key)
+				continue
+			}
+
+			if owner, ok := t.keys.refreshTokenOwnerFromKey(key); ok {
+				terminated, err := t.terminateRefreshTokens(ctx, owner)
+				if terminated {
+					deleted++
+				}
+				if err != nil {
+					return deleted, err
+				}
+			}
+		}
+
+		if len(tokenKeys) > 0 {
+			count, err := t.destroyKeys(ctx, tokenKeys, "")
+			deleted += count
+			if err != nil {
+				return deleted, err
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return deleted, nil
+}
+
+// destroyKeys deletes the plain tokens stored under a batch of keys in one pipeline and removes them from their
+// index. Keys which don't hold a plain token are left alone. Like DestroyPlainTokenWithReason every deleted token
+// gets a tombstone with the reason and an AuditRevoked entry.
+func (t *authManager) destroyKeys(ctx context.Context, keys []string, reason string) (int, error) {
+	getPipe := t.redisClient.Pipeline()
+	getCmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		getCmds[i] = getPipe.Get(ctx, key)
+	}
+	// Keys which are not plain tokens fail with WRONGTYPE or redis.Nil, those are inspected one by one below.
+	_, _ = getPipe.Exec(ctx)
+
+	type destroyedToken struct {
+		token  string
+		claims *TokenPayload
+		delCmd *redis.IntCmd
+	}
+
+	destroyed := make([]destroyedToken, 0, len(keys))
+	delPipe := t.redisClient.TxPipeline()
+	for i, cmd := range getCmds {
+		claimsString, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+
+		token, ok := t.keys.plainTokenFromKey(keys[i])
+		if !ok {
+			continue
+		}
+
+		// A corrupted entry is deleted all the same, it just has no owner to update.
+		claims, _ := t.decodePayload(claimsString)
+		if claims != nil {
+			delPipe.SRem(ctx, t.keys.generateIndexKey(claims.UUID), token)
+		}
+		destroyed = append(destroyed, destroyedToken{token: token, claims: claims, delCmd: delPipe.Del(ctx, keys[i])})
+	}
+	if len(destroyed) == 0 {
+		return 0, nil
+	}
+
+	_, err := delPipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	owners := map[string]struct{}{}
+	tombstoneTokens := []string{}
+	tokenHashes := make([]string, 0, len(destroyed))
+	auditEntries := []AuditEntry{}
+	for _, entry := range destroyed {
+		t.cache.evict(entry.token)
+		tokenHashes = append(tokenHashes, HashToken(entry.token))
+
+		if entry.claims != nil {
+			owners[entry.claims.UUID] = struct{}{}
+		}
+		if entry.delCmd.Val() == 0 {
+			continue
+		}
+
+		deleted++
+		tombstoneTokens = append(tombstoneTokens, entry.token)
+		if entry.claims != nil {
+			auditEntries = append(auditEntries, AuditEntry{Event: AuditRevoked, UUID: entry.claims.UUID, TokenType: entry.claims.TokenType})
+		}
+	}
+	t.publishInvalidation(ctx, invalidationMessage{TokenHashes: tokenHashes})
+	t.writeTombstones(ctx, tombstoneTokens, reason)
+	t.audit(ctx, auditEntries...)
+
+	for owner := range owners {
+		_, err = t.pruneActiveUser(ctx, owner)
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// RebuildUserIndex reconstructs the per-user indexes of plain tokens from the tokens stored in Redis.
+// Every live token is added to its owner's index, then entries of tokens which are gone or belong to
+// another user are removed. The keyspace is walked in SCAN batches and live tokens are never dropped
+// from an index, so it is safe to run while tokens are being issued.
+func (t *authManager) RebuildUserIndex(ctx context.Context) error {
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generatePlainTokenKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		tokens := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if token, ok := t.keys.plainTokenFromKey(key); ok {
+				tokens = append(tokens, token)
+			}
+		}
+
+		owners := t.tokenOwners(ctx, tokens)
+		_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, owner := range owners {
+				if owner != "" {
+					pipe.SAdd(ctx, t.keys.generateIndexKey(owner), tokens[i])
+					pipe.SAdd(ctx, t.keys.activeUsersKey(), owner)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return t.reconcileIndexes(ctx)
+}
+
+// reconcileIndexes removes the entries of every index which are not live tokens of the index's user. Unlike
+// RebuildUserIndex it only walks the index keys and never adds an entry.
+func (t *authManager) reconcileIndexes(ctx context.Context) error {
+	var cursor uint64
+	for {
+		indexKeys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generateIndexKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, indexKey := range indexKeys {
+			err = t.rebuildIndex(ctx, strings.TrimPrefix(indexKey, t.keys.generateIndexKey("")))
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// rebuildIndex removes the entries of the user's index which are not live tokens of that user.
+func (t *authManager) rebuildIndex(ctx context.Context, uuid string) error {
+	tokens, err := t.redisClient.SMembers(ctx, t.keys.generateIndexKey(uuid)).Result()
+	if err != nil {
+		return err
+	}
+
+	stale := []interface{}{}
+	for i, owner := range t.tokenOwners(ctx, tokens) {
+		if owner != uuid {
+			stale = append(stale, tokens[i])
+		}
+	}
+	if len(stale) > 0 {
+		err = t.redisClient.SRem(ctx, t.keys.generateIndexKey(uuid), stale...).Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = t.pruneActiveUser(ctx, uuid)
+	return err
+}
+
+// tokenOwners reads the uuid of each plain token in one pipeline, an empty string for keys which are not plain tokens.
+func (t *authManager) tokenOwners(ctx context.Context, tokens []string) []string {
+	owners := make([]string, len(tokens))
+	if len(tokens) == 0 {
+		return owners
+	}
+
+	getPipe := t.redisClient.Pipeline()
+	getCmds := make([]*redis.StringCmd, len(tokens))
+	for i, token := range tokens {
+		getCmds[i] = getPipe.Get(ctx, t.keys.generatePlainTokenKey(token))
+	}
+	// Keys which are gone or not plain tokens fail with redis.Nil or WRONGTYPE and have no owner.
+	_, _ = getPipe.Exec(ctx)
+
+	for i, cmd := range getCmds {
+		claimsString, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+
+		if claims, err := t.decodePayload(claimsString); err == nil {
+			owners[i] = claims.UUID
+		}
+	}
+
+	return owners
+}
@@ -0,0 +1,123 @@
+package auth_manager_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DestroyByPattern() {
+	ctx := context.TODO()
+	prefix := uuid.NewString()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  prefix,
+	})
+
+	tokens := make([]string, 3)
+	for i := range tokens {
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			TokenType: auth_manager.VerifyEmail,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		tokens[i] = token
+	}
+	refreshToken, err := manager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	accessClaims, err := manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), manager.RevokeByJTI(ctx, accessClaims.ID))
+
+	// Keys outside of the prefix belong to someone else
+	otherToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	otherKey := fmt.Sprintf("keep:%s", prefix)
+	require.NoError(s.T(), redisClient.Set(ctx, otherKey, "value", time.Minute).Err())
+
+	// A single token is matched by itself
+	deleted, err := manager.DestroyByPattern(ctx, tokens[0])
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 1, deleted)
+
+	// The two remaining plain tokens and the refresh token hash are all of the prefix which is deleted
+	deleted, err = manager.DestroyByPattern(ctx, "*")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 3, deleted)
+
+	for _, token := range tokens {
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+		isMember, err := redisClient.SIsMember(ctx, prefix+":plain_token_index:"+userUUID, token).Result()
+		require.NoError(s.T(), err)
+		require.False(s.T(), isMember)
+	}
+	_, err = manager.DecodeRefreshToken(ctx, userUUID, refreshToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// The revocation is kept, the access token stays revoked
+	_, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+
+	_, err = s.authManager.DecodePlainToken(ctx, otherToken, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	exists, err := redisClient.Exists(ctx, otherKey).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), exists)
+}
+
+func (s *AuthManagerTestSuite) Test_RebuildUserIndex() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	otherUUID := uuid.NewString()
+	indexKey := "plain_token_index:" + userUUID
+
+	tokens := make([]string, 3)
+	for i := range tokens {
+		token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		tokens[i] = token
+	}
+	otherToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      otherUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Corrupt the index: drop a live token, add a token which doesn't exist and one of another user
+	err = redisClient.SRem(ctx, indexKey, tokens[0]).Err()
+	require.NoError(s.T(), err)
+	err = redisClient.SAdd(ctx, indexKey, "missing-token", otherToken).Err()
+	require.NoError(s.T(), err)
+	err = redisClient.Del(ctx, "plain_token_index:"+otherUUID).Err()
+	require.NoError(s.T(), err)
+
+	err = s.authManager.RebuildUserIndex(ctx)
+	require.NoError(s.T(), err)
+
+	index, err := redisClient.SMembers(ctx, indexKey).Result()
+	require.NoError(s.T(), err)
+	require.ElementsMatch(s.T(), tokens, index)
+
+	index, err = redisClient.SMembers(ctx, "plain_token_index:"+otherUUID).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{otherToken}, index)
+}
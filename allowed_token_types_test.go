@@ -0,0 +1,57 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_AllowedTokenTypes() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:        "private-key",
+		AllowedTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+	})
+	payload := &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	_, err = manager.GenerateTokenResponse(ctx, auth_manager.VerifyEmail, payload, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	_, err = manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	_, err = manager.GenerateTokenResponse(ctx, auth_manager.AccessToken, payload, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	_, err = manager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	_, _, err = manager.GenerateTokenPair(ctx, userUUID, nil, time.Minute, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	_, err = manager.GenerateServiceToken(ctx, "billing-cron", nil, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+
+	// Nothing was issued for the disallowed types
+	activeTokens, err := manager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 2)
+
+	// Only generation is restricted, tokens issued elsewhere still decode
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	_, err = manager.ReissueToken(ctx, accessToken, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+}
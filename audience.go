@@ -0,0 +1,33 @@
+package auth_manager
+
+import (
+	"context"
+	"path"
+)
+
+// DecodeTokenForAudience decodes the access token like DecodeAccessToken and also requires one of the audiences
+// of its aud claim to be the given audience, or to match it as a glob pattern with AudienceWildcards.
+// ErrInvalidAudience is returned otherwise.
+func (t *authManager) DecodeTokenForAudience(ctx context.Context, token string, audience string) (*AccessTokenClaims, error) {
+	claims, err := t.DecodeAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tokenAudience := range claims.Audience {
+		if t.matchAudience(audience, tokenAudience) {
+			return claims, nil
+		}
+	}
+
+	return nil, t.disclose(ErrInvalidAudience)
+}
+
+func (t *authManager) matchAudience(audience string, tokenAudience string) bool {
+	if !t.opts.AudienceWildcards {
+		return audience == tokenAudience
+	}
+
+	matched, err := path.Match(audience, tokenAudience)
+	return err == nil && matched
+}
@@ -0,0 +1,57 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenForAudience() {
+	ctx := context.TODO()
+
+	newManager := func(wildcards bool) auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:        "private-key",
+			Audience:          []string{"api.users", "billing"},
+			AudienceWildcards: wildcards,
+		})
+	}
+	exact := newManager(false)
+	wildcard := newManager(true)
+
+	token, err := exact.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	// Exact match
+	claims, err := exact.DecodeTokenForAudience(ctx, token, "billing")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"api.users", "billing"}, []string(claims.Audience))
+
+	// Patterns are taken literally unless AudienceWildcards is set
+	_, err = exact.DecodeTokenForAudience(ctx, token, "api.*")
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidAudience)
+
+	_, err = wildcard.DecodeTokenForAudience(ctx, token, "api.*")
+	require.NoError(s.T(), err)
+
+	_, err = wildcard.DecodeTokenForAudience(ctx, token, "billing")
+	require.NoError(s.T(), err)
+
+	// No match
+	_, err = exact.DecodeTokenForAudience(ctx, token, "api")
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidAudience)
+
+	_, err = wildcard.DecodeTokenForAudience(ctx, token, "admin.*")
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidAudience)
+
+	// A token without audience never matches
+	noAudience, err := s.authManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = wildcard.DecodeTokenForAudience(ctx, noAudience, "*")
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidAudience)
+}
@@ -0,0 +1,111 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type AuditEvent string
+
+const (
+	AuditIssued  AuditEvent = "issued"
+	AuditRevoked AuditEvent = "revoked"
+)
+
+// AuditEntry is a token issuance or revocation recorded when AuthManagerOpts.AuditLogLength is set.
+// JTI is only set for access tokens.
+type AuditEntry struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Event     AuditEvent `json:"event"`
+	UUID      string     `json:"uuid"`
+	JTI       string     `json:"jti,omitempty"`
+	TokenType TokenType  `json:"tokenType"`
+}
+
+// audit appends the entries to their uuid's audit log. The audit trail is best effort:
+// a failing write is dropped and never fails the operation being audited.
+func (t *authManager) audit(ctx context.Context, entries ...AuditEntry) {
+	if t.opts.AuditLogLength <= 0 || len(entries) == 0 {
+		return
+	}
+
+	now := time.Now()
+	_, _ = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, entry := range entries {
+			entry.Timestamp = now
+
+			encodedEntry, err := t.codec.Marshal(entry)
+			if err != nil {
+				continue
+			}
+
+			pipe.LPush(ctx, t.keys.generateAuditKey(entry.UUID), encodedEntry)
+			pipe.LTrim(ctx, t.keys.generateAuditKey(entry.UUID), 0, int64(t.opts.AuditLogLength-1))
+		}
+		return nil
+	})
+}
+
+// auditAccessToken records the issuance of an access token along with the owner of its jti.
+func (t *authManager) auditAccessToken(ctx context.Context, uuid string, jti string, expiresAt time.Duration) {
+	if t.opts.AuditLogLength <= 0 {
+		return
+	}
+
+	_ = t.redisClient.Set(ctx, t.keys.generateAuditOwnerKey(jti), uuid, expiresAt).Err()
+	t.audit(ctx, AuditEntry{Event: AuditIssued, UUID: uuid, JTI: jti, TokenType: AccessToken})
+}
+
+// auditRevokedJTIs records the revocation of the access tokens whose owner is still known.
+func (t *authManager) auditRevokedJTIs(ctx context.Context, jtis []string) {
+	if t.opts.AuditLogLength <= 0 {
+		return
+	}
+
+	keys := make([]string, len(jtis))
+	for i, jti := range jtis {
+		keys[i] = t.keys.generateAuditOwnerKey(jti)
+	}
+
+	owners, err := t.redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return
+	}
+
+	entries := make([]AuditEntry, 0, len(jtis))
+	for i, owner := range owners {
+		if uuid, ok := owner.(string); ok {
+			entries = append(entries, AuditEntry{Event: AuditRevoked, UUID: uuid, JTI: jtis[i], TokenType: AccessToken})
+		}
+	}
+
+	t.audit(ctx, entries...)
+}
+
+// AuditLog returns the recent audit entries of the uuid, newest first. It is empty unless AuditLogLength is set.
+func (t *authManager) AuditLog(ctx context.Context, uuid string) ([]AuditEntry, error) {
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedEntries, err := t.redisClient.LRange(ctx, t.keys.generateAuditKey(uuid), 0, -1).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, len(encodedEntries))
+	for _, encodedEntry := range encodedEntries {
+		var entry AuditEntry
+		if err := t.codec.Unmarshal([]byte(encodedEntry), &entry); err != nil {
+			return nil, ErrCorruptedEntry
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
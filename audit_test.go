@@ -0,0 +1,76 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_AuditLog() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:     "private-key",
+		AuditLogLength: 4,
+	})
+
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	plainToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	err = manager.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+
+	err = manager.DestroyPlainToken(ctx, plainToken)
+	require.NoError(s.T(), err)
+
+	entries, err := manager.AuditLog(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), entries, 4)
+
+	// Newest first
+	require.Equal(s.T(), auth_manager.AuditRevoked, entries[0].Event)
+	require.Equal(s.T(), auth_manager.ResetPassword, entries[0].TokenType)
+	require.Equal(s.T(), auth_manager.AuditRevoked, entries[1].Event)
+	require.Equal(s.T(), claims.ID, entries[1].JTI)
+	require.Equal(s.T(), auth_manager.AuditIssued, entries[2].Event)
+	require.Equal(s.T(), auth_manager.ResetPassword, entries[2].TokenType)
+	require.Equal(s.T(), auth_manager.AuditIssued, entries[3].Event)
+	require.Equal(s.T(), auth_manager.AccessToken, entries[3].TokenType)
+	require.Equal(s.T(), claims.ID, entries[3].JTI)
+	for _, entry := range entries {
+		require.Equal(s.T(), userUUID, entry.UUID)
+		require.WithinDuration(s.T(), time.Now(), entry.Timestamp, time.Minute)
+	}
+
+	// The log is capped at AuditLogLength
+	_, err = manager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	entries, err = manager.AuditLog(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), entries, 4)
+	require.Equal(s.T(), auth_manager.AuditIssued, entries[0].Event)
+	require.Equal(s.T(), auth_manager.RefreshToken, entries[0].TokenType)
+
+	// Nothing is recorded when the audit trail is disabled
+	otherUUID := uuid.NewString()
+	_, err = s.authManager.GenerateAccessToken(ctx, otherUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	entries, err = s.authManager.AuditLog(ctx, otherUUID)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), entries)
+}
@@ -2,9 +2,16 @@ package auth_manager
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	googleuuid "github.com/google/uuid"
 )
 
 const TokenByteLength = 32
@@ -16,36 +23,473 @@ const (
 	VerifyEmail
 	AccessToken
 	RefreshToken
+	// ServiceToken is the type of the tokens of machine clients, see GenerateServiceToken.
+	ServiceToken
 )
 
+// valid reports whether the token type is one of the defined constants.
+func (t TokenType) valid() bool {
+	return t >= ResetPassword && t <= ServiceToken
+}
+
+// plain reports whether tokens of this type are issued as plain tokens.
+func (t TokenType) plain() bool {
+	return t.valid() && t != AccessToken && t != RefreshToken && t != ServiceToken
+}
+
+var tokenTypeNames = map[TokenType]string{
+	ResetPassword: "reset_password",
+	VerifyEmail:   "verify_email",
+	AccessToken:   "access_token",
+	RefreshToken:  "refresh_token",
+	ServiceToken:  "service_token",
+}
+
+// String returns the canonical name of the token type, as accepted by ParseTokenType.
+func (t TokenType) String() string {
+	if name, ok := tokenTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// ParseTokenType returns the token type with the canonical name, for token types read from config files or flags.
+func ParseTokenType(s string) (TokenType, error) {
+	for tokenType, name := range tokenTypeNames {
+		if name == s {
+			return tokenType, nil
+		}
+	}
+
+	return 0, ErrInvalidTokenType
+}
+
 type AuthManager interface {
 	GenerateAccessToken(ctx context.Context, uuid string, expiresAt time.Duration) (string, error)
 	DecodeAccessToken(ctx context.Context, token string) (*AccessTokenClaims, error)
+	GenerateServiceToken(ctx context.Context, serviceID string, scopes []string, expiresAt time.Duration) (string, error)
+	DecodeServiceToken(ctx context.Context, token string) (*ServiceTokenClaims, error)
+	RevokeByJTI(ctx context.Context, jtis ...string) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
 	GenerateRefreshToken(ctx context.Context, uuid string, payload *RefreshTokenPayload, expiresAt time.Duration) (string, error)
+	GenerateTokenPair(ctx context.Context, uuid string, payload *RefreshTokenPayload, accessExpiresAt time.Duration, refreshExpiresAt time.Duration) (string, string, error)
 	TerminateRefreshTokens(ctx context.Context, uuid string) error
 	RemoveRefreshToken(ctx context.Context, uuid string, token string) error
 	DecodeRefreshToken(ctx context.Context, uuid string, token string) (*RefreshTokenPayload, error)
 	GeneratePlainToken(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, error)
+	GeneratePlainTokenWithHash(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, string, error)
 	DecodePlainToken(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error)
 	DestroyPlainToken(ctx context.Context, key string) error
+	DestroyPlainTokenExists(ctx context.Context, key string) (bool, error)
+	DestroyPlainTokenWithReason(ctx context.Context, key string, reason string) (bool, error)
+	DecodeTokenAllowing(ctx context.Context, token string, allowed ...TokenType) (*TokenPayload, error)
+	DecodeTokenForPurpose(ctx context.Context, token string, tokenType TokenType, purpose string) (*TokenPayload, error)
+	DecodeTokenWithVerifier(ctx context.Context, token string, tokenType TokenType, verifier string) (*TokenPayload, error)
+	DecodeTokenMinAge(ctx context.Context, token string, tokenType TokenType, minAge time.Duration) (*TokenPayload, error)
+	DecodeWrappedToken(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error)
+	DecodeTokenReader(ctx context.Context, r io.Reader, tokenType TokenType) (*TokenPayload, error)
+	GenerateQRPayload(ctx context.Context, baseURL string, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, error)
+	DecodeQRPayload(ctx context.Context, qrPayload string, tokenType TokenType) (*TokenPayload, error)
+	GenerateTokenWithShortCode(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, string, error)
+	DecodeByShortCode(ctx context.Context, code string, tokenType TokenType) (*TokenPayload, error)
+	Close() error
+	DecodeTokenRaw(ctx context.Context, token string, tokenType TokenType) (jwt.MapClaims, error)
+	DecodeTokenDetailed(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, *ValidationReport, error)
+	GetIssuedAt(token string) (time.Time, error)
+	ValidateBatch(ctx context.Context, tokens []string, tokenType TokenType) ([]BatchResult, error)
+	ListActiveTokens(ctx context.Context, uuid string) ([]ActiveToken, error)
+	TouchToken(ctx context.Context, token string) error
+	DestroyByPattern(ctx context.Context, pattern string) (int, error)
+	EnumerateActiveUsers(ctx context.Context) ([]string, error)
+	CountByTokenType(ctx context.Context) (map[TokenType]int, error)
+	RebuildUserIndex(ctx context.Context) error
+	PurgeOrphans(ctx context.Context, olderThan time.Duration) (int, error)
+	ExportTokens(ctx context.Context) (<-chan ExportedToken, error)
+	ImportTokens(ctx context.Context, tokens <-chan ExportedToken) (int, error)
+	AuditLog(ctx context.Context, uuid string) ([]AuditEntry, error)
+	ReissueToken(ctx context.Context, token string, expiresAt time.Duration) (string, error)
+	CreateSession(ctx context.Context, uuid string, expiresAt time.Duration) (string, error)
+	GenerateSessionAccessToken(ctx context.Context, sessionID string, expiresAt time.Duration) (string, error)
+	DestroySession(ctx context.Context, sessionID string) error
+	DecodeTokenForAudience(ctx context.Context, token string, audience string) (*AccessTokenClaims, error)
+	BumpUserEpoch(ctx context.Context, uuid string) error
+	ForceLogout(ctx context.Context, uuid string) error
+	SetUserValidUntil(ctx context.Context, uuid string, validUntil time.Time) error
+	RotatePrivateKey(key string) error
+	ReloadKey() error
+	SubscribeInvalidations(ctx context.Context) (io.Closer, error)
+	GenerateTokenHandle(ctx context.Context, jwtToken string) (string, error)
+	ResolveToken(ctx context.Context, handle string) (string, error)
+	MigrateSigningKey(ctx context.Context, oldKey string, newKey string) (int, error)
+	GenerateTokenResponse(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (*TokenResponse, error)
 }
 
 type AuthManagerOpts struct {
 	PrivateKey string
+
+	// PrivateKeyFile is the path of a file holding the PrivateKey, e.g. a secret mounted into the container, read
+	// in place of PrivateKey by the constructor and again by ReloadKey. Surrounding whitespace is trimmed.
+	PrivateKeyFile string
+
+	// VerificationKeys are previous secrets which are still accepted when verifying access tokens,
+	// so rolling over PrivateKey does not invalidate tokens in flight. Signing always uses PrivateKey.
+	VerificationKeys []string
+
+	// MaxExpiryHorizon rejects jwt with ErrInvalidToken when their exp is further in the future than this duration,
+	// e.g. a forged token with an absurd lifetime. Zero disables the check. A jwt without exp is always rejected.
+	MaxExpiryHorizon time.Duration
+
+	// TTLJitter spreads the expiries of plain tokens generated in a burst, e.g. by a mass email, by shortening or
+	// lengthening each lifetime by a random fraction of up to TTLJitter, e.g. 0.1 for ±10%. The jittered lifetime
+	// never exceeds MaxExpiryHorizon nor the validity of the user. Zero disables the jitter.
+	TTLJitter float64
+
+	// ShortCodeLength is the number of digits of the codes of GenerateTokenWithShortCode, defaults to 6.
+	ShortCodeLength int
+
+	// JanitorInterval drops the index entries of tokens which are gone in the background at about this interval,
+	// and runs PurgeOrphans when JanitorOrphanAge is set, until Close is called. Intervals below a second are raised
+	// to one, and each wait is jittered by up to 10%. RebuildUserIndex is never run in the background, it walks
+	// every token. Zero disables the background maintenance.
+	JanitorInterval time.Duration
+
+	// JanitorOrphanAge is the olderThan of the PurgeOrphans of the background maintenance. It must cover the
+	// lifetime of the longest living access token, zero skips the purge.
+	JanitorOrphanAge time.Duration
+
+	// MaxTokenAge rejects access tokens issued longer ago than this duration, regardless of their expiration.
+	// Zero disables the check.
+	MaxTokenAge time.Duration
+
+	// RevocationTTL is how long a jti revoked by RevokeByJTI is remembered. Defaults to a week.
+	RevocationTTL time.Duration
+
+	// DisclosurePolicy decides whether the decode methods return the specific reason a token was rejected
+	// or ErrInvalidToken alone. Defaults to DiscloseDetailed.
+	DisclosurePolicy DisclosurePolicy
+
+	// AllowedTokenTypes restricts the token types the manager issues, e.g. only VerifyEmail in an email service.
+	// Generating a token of another type returns ErrUnsupportedTokenType. Empty allows every type.
+	AllowedTokenTypes []TokenType
+
+	// StatelessTokenTypes are plain token types issued as signed jwt which are never stored in Redis.
+	// Decoding them relies on the signature and expiration alone, so they can't be destroyed before they expire.
+	StatelessTokenTypes []TokenType
+
+	// TrackStatelessTokens records stateless tokens in Redis under their jti, which is embedded in the jwt, so
+	// decoding still only takes the token and DestroyPlainToken can invalidate them before they expire.
+	TrackStatelessTokens bool
+
+	// AcceptOnRedisMiss accepts a tracked stateless token whose Redis entry is missing, e.g. evicted under memory
+	// pressure, relying on its signature and expiration. Destroying the token then records its jti as revoked instead,
+	// so server side logout only holds as long as that revocation entry survives in Redis.
+	AcceptOnRedisMiss bool
+
+	// SeparateHandleSignature stores the jwt behind handles of GenerateTokenHandle as its claims and its signature
+	// in separate fields, so MigrateSigningKey only rewrites the signature. Handles stored in either layout resolve.
+	SeparateHandleSignature bool
+
+	// DeterministicPlainTokens derives plain tokens from the uuid, type and purpose of their claims with an HMAC of the
+	// signing secret instead of drawing them at random, so generating the token of a flow again replaces the previous
+	// one instead of adding another. Tokens stay unguessable without the secret, but generating a token again hands
+	// out the same one: a leaked token can't be made useless by issuing a new one, only by destroying it.
+	// Requires PrivateKey or MasterKey.
+	DeterministicPlainTokens bool
+
+	// SignTokenMeta embeds the TokenMeta of stateless tokens in the signed jwt instead of dropping it.
+	SignTokenMeta bool
+
+	// SoftDeleteGracePeriod keeps a tombstone of destroyed plain tokens for that long, so decoding one returns
+	// ErrTokenRevoked along with the reason it was destroyed instead of ErrTokenExpired. Zero disables tombstones.
+	SoftDeleteGracePeriod time.Duration
+
+	// EqualizeMissTiming decodes a stand-in entry when a plain token is not found in Redis, so answering for a
+	// missing token costs about the same as for an existing one and the decode time tells less about which tokens
+	// exist. A miss also reads the user epoch of the stand-in when UserEpochs is set, and with SoftDeleteGracePeriod
+	// the tombstone is read along with the token, so both take the same Redis round-trips. It is a hardening
+	// measure which makes every miss slightly slower.
+	EqualizeMissTiming bool
+
+	// CacheSize enables an in-process LRU cache of that many decode results, so repeated decodes
+	// of a hot token skip Redis. Destroyed and revoked tokens are evicted from the local cache, and from the caches
+	// of the other instances when InvalidationChannel is set and they run SubscribeInvalidations.
+	CacheSize int
+
+	// CacheTTL is how long a decode result is cached, never beyond the expiration of the token. Defaults to 5 seconds.
+	CacheTTL time.Duration
+
+	// KeyPrefix is prepended to every Redis key, so several applications can share a Redis.
+	KeyPrefix string
+
+	// KeySeparator separates the prefix, the kind and the id of Redis keys. Defaults to ":".
+	KeySeparator string
+
+	// InvalidationChannel is a Redis Pub/Sub channel on which revoked and destroyed tokens are published, so
+	// instances listening with SubscribeInvalidations evict them from their local cache too.
+	InvalidationChannel string
+
+	// Codec serializes the values stored in Redis, defaults to JSONCodec.
+	// It must be the same for every instance sharing the Redis.
+	Codec Codec
+
+	// UUIDNormalizer brings the uuid of generated tokens into a canonical form, e.g. lowercase, before it is stored
+	// in claims and indexes, so "ABC" and "abc" are the same user. Lookups by uuid are normalized too. It must be
+	// idempotent and its error rejects the uuid. StrictUUID applies to the normalized uuid.
+	UUIDNormalizer func(uuid string) (string, error)
+
+	// StrictUUID requires the uuid of generated tokens to parse as a UUID. By default any non-empty id is accepted.
+	StrictUUID bool
+
+	// Signer signs and verifies jwt through an external key provider instead of PrivateKey.
+	Signer Signer
+
+	// TokenSigners sign the jwt of some token types with their own Signer instead of the default one, e.g. RS256
+	// for reset tokens verified by other services while access tokens keep the faster HMAC. A token is only
+	// accepted with the method configured for the type it claims.
+	TokenSigners map[TokenType]Signer
+
+	// AuditLogLength records the issuance and revocation of tokens in a Redis list per uuid, keeping that many
+	// recent entries. Zero disables the audit trail.
+	AuditLogLength int
+
+	// MasterKey replaces PrivateKey with a secret derived per TenantID, so a new tenant needs no key distribution.
+	// Tokens of one tenant don't validate for another. VerificationKeys are then previous master keys.
+	MasterKey string
+
+	// TenantID selects the secret derived from MasterKey.
+	TenantID string
+
+	// MaxTokenBytes rejects larger tokens with ErrTokenTooLarge before they are parsed. Defaults to 16KiB.
+	MaxTokenBytes int
+
+	// VerifySession makes DecodeAccessToken check that the session of the token still exists, so short lived
+	// access tokens can be revoked server side with DestroySession. Tokens issued without a session are rejected.
+	VerifySession bool
+
+	// RedisUnavailablePolicy decides whether access tokens are still accepted when Redis is down. Defaults to FailClosed.
+	RedisUnavailablePolicy RedisUnavailablePolicy
+
+	// Audience is set as the aud claim of generated access tokens, checked by DecodeTokenForAudience.
+	Audience []string
+
+	// AudienceWildcards lets DecodeTokenForAudience take a glob pattern such as "api.*" matched against
+	// each audience of the token. By default the audience must match exactly.
+	AudienceWildcards bool
+
+	// SingleSession allows a single access token per user: issuing one makes DecodeAccessToken reject the
+	// previous ones with ErrSessionSuperseded. Tokens issued before SingleSession was enabled are rejected too.
+	SingleSession bool
+
+	// UserEpochs stamps the user's current epoch into generated tokens and rejects tokens of an older epoch
+	// with ErrEpochMismatch, so BumpUserEpoch invalidates every token of the user at once.
+	UserEpochs bool
+
+	// UserValidity clamps the lifetime of generated access and plain tokens, and of sessions, to the validity set
+	// for their user with SetUserValidUntil. Generating for a user whose validity ended returns ErrUserExpired.
+	UserValidity bool
+
+	// PlainTokenMaxAge rejects plain tokens stored in Redis whose CreatedAt or IssuedAt is older than the max age of
+	// their type with ErrTokenTooOld, even while their Redis TTL runs. Stateless plain tokens are bound by their exp claim.
+	PlainTokenMaxAge map[TokenType]time.Duration
+
+	// PersistHook is called with every generated plain token once it is written to Redis, e.g. to keep a record
+	// of issued tokens in a database. When it fails the token is removed from Redis again and generating it fails.
+	PersistHook PersistHook
+
+	// ClaimsValidator runs custom rules, e.g. rejecting suspended users, once access and plain tokens passed
+	// every other check. A returned error rejects the token and is wrapped together with ErrInvalidToken.
+	ClaimsValidator ClaimsValidator
+
+	// CountSampleSize makes CountByTokenType estimate the counts from that many random keys instead of reading
+	// every token. Zero counts exactly.
+	CountSampleSize int
+
+	// Metrics receives the age of every successfully decoded access and plain token, labeled by token type.
+	Metrics Metrics
+}
+
+// Validate checks that PrivateKey fits TokenEncodingAlgorithm, unless a Signer is used. Only HMAC algorithms are supported,
+// which take a plain secret, so a PEM encoded key is reported as ErrKeyAlgorithmMismatch.
+func (o AuthManagerOpts) Validate() error {
+	if o.Signer != nil {
+		return nil
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(o.signingSecret()), "-----BEGIN") {
+		return ErrKeyAlgorithmMismatch
+	}
+
+	return nil
+}
+
+// checkAllowedTokenType rejects the generation of tokens of a type outside AllowedTokenTypes.
+func (o AuthManagerOpts) checkAllowedTokenType(tokenType TokenType) error {
+	if len(o.AllowedTokenTypes) > 0 && !slices.Contains(o.AllowedTokenTypes, tokenType) {
+		return ErrUnsupportedTokenType
+	}
+
+	return nil
+}
+
+// validateUUID normalizes the uuid of a generated token, then rejects empty uuids and, with StrictUUID,
+// the ones which are not valid UUIDs.
+func (o AuthManagerOpts) validateUUID(id string) (string, error) {
+	id, err := o.normalizeUUID(id)
+	if err != nil {
+		return "", err
+	}
+
+	if id == "" {
+		return "", ErrEmptyUUID
+	}
+
+	if o.StrictUUID {
+		if _, err := googleuuid.Parse(id); err != nil {
+			return "", ErrMalformedUUID
+		}
+	}
+
+	return id, nil
+}
+
+// normalizeUUID applies the UUIDNormalizer, if any, to a uuid tokens are looked up by.
+func (o AuthManagerOpts) normalizeUUID(id string) (string, error) {
+	if o.UUIDNormalizer == nil {
+		return id, nil
+	}
+
+	return o.UUIDNormalizer(id)
+}
+
+// validateExpiry rejects lifetimes which would issue an already expired token or a Redis key without expiration.
+func validateExpiry(expiresAt time.Duration) error {
+	if expiresAt <= 0 {
+		return ErrInvalidExpiry
+	}
+
+	return nil
 }
 
 // Used as jwt claims
 type TokenPayload struct {
-	UUID      string    `json:"uuid"`
-	CreatedAt time.Time `json:"createdAt"`
-	TokenType TokenType `json:"tokenType"`
+	UUID      string     `json:"uuid"`
+	CreatedAt time.Time  `json:"createdAt"`
+	TokenType TokenType  `json:"tokenType"`
+	Meta      *TokenMeta `json:"meta,omitempty"`
+
+	// Purpose narrows the flow a plain token was issued for within its type, see DecodeTokenForPurpose.
+	Purpose string `json:"purpose,omitempty"`
+
+	// Epoch is the user's epoch when the token was issued, see AuthManagerOpts.UserEpochs.
+	Epoch int64 `json:"epoch,omitempty"`
+
+	// CodeChallenge binds a plain token to a code verifier, see DecodeTokenWithVerifier.
+	CodeChallenge string `json:"codeChallenge,omitempty"`
+
+	// IssuedAt is set by GeneratePlainToken to the time the token was generated, replacing any value of the caller.
+	// Unlike CreatedAt it can't be chosen by the caller, so the age checks of plain tokens rely on it.
+	IssuedAt time.Time `json:"issuedAt,omitempty"`
+}
+
+// Validate checks the structure of the claims, independently of any signature: the uuid is set, the token type
+// is one of the defined constants and CreatedAt is set and not in the future. Generated tokens are validated
+// before they are signed or stored.
+func (p *TokenPayload) Validate() error {
+	if p.UUID == "" {
+		return ErrEmptyUUID
+	}
+	if !p.TokenType.valid() {
+		return ErrInvalidTokenType
+	}
+	if p.CreatedAt.IsZero() || p.CreatedAt.After(time.Now()) {
+		return ErrInvalidCreatedAt
+	}
+
+	return nil
+}
+
+// TokenMeta describes the request a plain token was issued for, kept for later audit.
+// It is stored in Redis with the claims but left out of stateless tokens unless SignTokenMeta is set.
+type TokenMeta struct {
+	IPAddress string `json:"ipAddress"`
+	UserAgent string `json:"userAgent"`
+	DeviceID  string `json:"deviceId"`
+
+	// LastUsed is updated by TouchToken.
+	LastUsed time.Time `json:"lastUsed"`
 }
 
 type authManager struct {
 	redisClient *redis.Client
 	opts        AuthManagerOpts
+
+	// Derived from opts once so the hot paths don't allocate them per call.
+	// Only the keys change afterwards, replaced by RotatePrivateKey under keysMu.
+	keysMu          sync.RWMutex
+	signingKey      []byte
+	verificationKey interface{}
+	parser          *jwt.Parser
+	cache           *decodeCache
+	codec           Codec
+	keys            keyBuilder
+	missEntry       string
+
+	// keyFileErr is the error of reading PrivateKeyFile in the constructor, returned by every token generation.
+	keyFileErr error
+
+	// janitor is the background maintenance of JanitorInterval, nil when it is disabled.
+	janitor *janitor
 }
 
+// NewAuthManager returns an AuthManager which is safe for concurrent use by multiple goroutines.
+// When PrivateKeyFile can't be read, generating tokens fails with the error; NewAuthManagerWithPing returns it.
 func NewAuthManager(redisClient *redis.Client, opts AuthManagerOpts) AuthManager {
-	return &authManager{redisClient, opts}
+	return newAuthManager(redisClient, opts)
+}
+
+func newAuthManager(redisClient *redis.Client, opts AuthManagerOpts) *authManager {
+	codec := opts.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	var keyFileErr error
+	if opts.PrivateKeyFile != "" {
+		opts.PrivateKey, keyFileErr = readKeyFile(opts.PrivateKeyFile)
+	}
+
+	t := &authManager{
+		redisClient:     redisClient,
+		opts:            opts,
+		signingKey:      opts.secret(opts.signingSecret()),
+		verificationKey: newVerificationKey(opts),
+		parser:          jwt.NewParser(jwt.WithExpirationRequired()),
+		cache:           newDecodeCache(opts.CacheSize, opts.CacheTTL),
+		codec:           codec,
+		keys:            newKeyBuilder(opts),
+		keyFileErr:      keyFileErr,
+	}
+	t.missEntry = t.newMissEntry()
+	if opts.JanitorInterval > 0 {
+		t.janitor = t.startJanitor(opts.JanitorInterval)
+	}
+
+	return t
+}
+
+// NewAuthManagerWithPing is NewAuthManager which pings Redis first, so a misconfigured or unreachable Redis fails
+// the startup of a deployment instead of its first token request. An unreadable PrivateKeyFile fails it too.
+func NewAuthManagerWithPing(ctx context.Context, redisClient *redis.Client, opts AuthManagerOpts) (AuthManager, error) {
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	t := newAuthManager(redisClient, opts)
+	if t.keyFileErr != nil {
+		_ = t.Close()
+		return nil, t.keyFileErr
+	}
+
+	return t, nil
 }
@@ -165,3 +165,60 @@ func (s *AuthManagerTestSuite) Test_RefreshToken() {
 func TestAuthManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthManagerTestSuite))
 }
+
+func (s *AuthManagerTestSuite) Test_CrossInstanceDecode() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.ResetPassword},
+		CacheSize:           16,
+	}
+	instanceA := auth_manager.NewAuthManager(redisClient, opts)
+	instanceB := auth_manager.NewAuthManager(redis.NewClient(redisClient.Options()), opts)
+
+	accessToken, err := instanceA.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	accessClaims, err := instanceB.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, accessClaims.Payload.UUID)
+
+	for _, tokenType := range []auth_manager.TokenType{auth_manager.VerifyEmail, auth_manager.ResetPassword} {
+		plainToken, err := instanceA.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			TokenType: tokenType,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		decoded, err := instanceB.DecodePlainToken(ctx, plainToken, tokenType)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), userUUID, decoded.UUID)
+	}
+
+	refreshToken, err := instanceA.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = instanceB.DecodeRefreshToken(ctx, userUUID, refreshToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_NewAuthManagerWithPing() {
+	ctx := context.TODO()
+	opts := auth_manager.AuthManagerOpts{PrivateKey: "private-key"}
+
+	manager, err := auth_manager.NewAuthManagerWithPing(ctx, redisClient, opts)
+	require.NoError(s.T(), err)
+
+	_, err = manager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	unreachableClient := redis.NewClient(&redis.Options{
+		Addr:       "127.0.0.1:1",
+		MaxRetries: -1,
+	})
+	defer unreachableClient.Close()
+
+	manager, err = auth_manager.NewAuthManagerWithPing(ctx, unreachableClient, opts)
+	require.Error(s.T(), err)
+	require.Nil(s.T(), manager)
+}
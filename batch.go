@@ -0,0 +1,162 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BatchResult is the outcome of validating a single token with ValidateBatch.
+type BatchResult struct {
+	Token string
+	Valid bool
+
+	// Error tells why the token is invalid, nil for a valid token.
+	Error error
+}
+
+// tokenErrors are the errors which reject a token, as opposed to a failure to check it.
+var tokenErrors = []error{
+	ErrInvalidToken,
+	ErrInvalidTokenType,
+	ErrUnsupportedTokenType,
+	ErrNoExpiration,
+	ErrTokenExpired,
+	ErrTokenRevoked,
+	ErrTokenTooOld,
+	ErrTokenTooYoung,
+	ErrTokenTooLarge,
+	ErrInvalidWrappedToken,
+	ErrEpochMismatch,
+	ErrSessionSuperseded,
+	ErrSessionExpired,
+	ErrCorruptedEntry,
+	ErrInvalidAudience,
+	ErrPurposeMismatch,
+	ErrChallengeMismatch,
+}
+
+// isTokenError reports whether the error of a decode rejects the token itself.
+func isTokenError(err error) bool {
+	for _, tokenErr := range tokenErrors {
+		if errors.Is(err, tokenErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateBatch validates each token as the decode method of the token type would, e.g. for a list of tokens
+// harvested from logs, and returns a result per token in the same order. Plain tokens stored in Redis are read in
+// a single pipeline, access and stateless tokens are verified one after the other. An invalid token is reported in
+// its result, the call itself only fails when the tokens can't be checked, e.g. because Redis is down.
+func (t *authManager) ValidateBatch(ctx context.Context, tokens []string, tokenType TokenType) ([]BatchResult, error) {
+	if !tokenType.valid() {
+		return nil, ErrInvalidTokenType
+	}
+	if tokenType == RefreshToken {
+		return nil, ErrUnsupportedTokenType
+	}
+
+	if tokenType.plain() && !t.stateless(tokenType) {
+		results, err := t.validateStoredBatch(ctx, tokens, tokenType)
+		for i := range results {
+			results[i].Error = t.disclose(results[i].Error)
+		}
+		return results, err
+	}
+
+	results := make([]BatchResult, len(tokens))
+	for i, token := range tokens {
+		var err error
+		if tokenType == AccessToken {
+			_, err = t.DecodeAccessToken(ctx, token)
+		} else if tokenType == ServiceToken {
+			_, err = t.DecodeServiceToken(ctx, token)
+		} else {
+			_, err = t.DecodePlainToken(ctx, token, tokenType)
+		}
+		if err != nil && !isTokenError(err) {
+			return nil, err
+		}
+
+		results[i] = BatchResult{Token: token, Valid: err == nil, Error: err}
+	}
+
+	return results, nil
+}
+
+func (t *authManager) validateStoredBatch(ctx context.Context, tokens []string, tokenType TokenType) ([]BatchResult, error) {
+	results := make([]BatchResult, len(tokens))
+	getCmds := make([]*redis.StringCmd, len(tokens))
+	_, err := t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, token := range tokens {
+			results[i].Token = token
+
+			token = trimToken(token)
+			if err := t.checkTokenSize(token); err != nil {
+				results[i].Error = err
+				continue
+			}
+			if isJWT(token) {
+				results[i].Error = ErrUnsupportedTokenType
+				continue
+			}
+			if !validOpaqueFormat(token, plainTokenLength) {
+				results[i].Error = ErrInvalidToken
+				continue
+			}
+
+			getCmds[i] = pipe.Get(ctx, t.keys.generatePlainTokenKey(token))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	for i, cmd := range getCmds {
+		if cmd == nil {
+			continue
+		}
+
+		err := t.validateStoredEntry(ctx, trimToken(tokens[i]), cmd, tokenType)
+		if err != nil && !isTokenError(err) {
+			return nil, err
+		}
+
+		results[i].Valid = err == nil
+		results[i].Error = err
+	}
+
+	return results, nil
+}
+
+// validateStoredEntry checks the claims read by the pipeline of validateStoredBatch like decodePlainToken.
+func (t *authManager) validateStoredEntry(ctx context.Context, token string, cmd *redis.StringCmd, tokenType TokenType) error {
+	claimsString, err := cmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return t.missingTokenError(ctx, token)
+	}
+	if err != nil {
+		return err
+	}
+
+	claims, err := t.decodePayload(claimsString)
+	if err != nil {
+		return err
+	}
+
+	if claims.TokenType != tokenType {
+		return ErrInvalidTokenType
+	}
+
+	err = t.checkStoredToken(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	return t.validateClaims(ctx, claims)
+}
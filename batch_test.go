@@ -0,0 +1,73 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ValidateBatch() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	generate := func(tokenType auth_manager.TokenType) string {
+		token, err := s.authManager.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	valid := generate(auth_manager.VerifyEmail)
+	destroyed := generate(auth_manager.VerifyEmail)
+	require.NoError(s.T(), s.authManager.DestroyPlainToken(ctx, destroyed))
+	otherType := generate(auth_manager.ResetPassword)
+
+	results, err := s.authManager.ValidateBatch(ctx, []string{valid, destroyed, "bogus", otherType, " " + valid}, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), results, 5)
+
+	require.Equal(s.T(), auth_manager.BatchResult{Token: valid, Valid: true}, results[0])
+	require.Equal(s.T(), destroyed, results[1].Token)
+	require.False(s.T(), results[1].Valid)
+	require.ErrorIs(s.T(), results[1].Error, auth_manager.ErrTokenExpired)
+	require.ErrorIs(s.T(), results[2].Error, auth_manager.ErrInvalidToken)
+	require.ErrorIs(s.T(), results[3].Error, auth_manager.ErrInvalidTokenType)
+	require.True(s.T(), results[4].Valid)
+
+	// Access tokens
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	revokedToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	claims, err := s.authManager.DecodeAccessToken(ctx, revokedToken)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.authManager.RevokeByJTI(ctx, claims.ID))
+
+	results, err = s.authManager.ValidateBatch(ctx, []string{accessToken, revokedToken, "a.b.c", valid}, auth_manager.AccessToken)
+	require.NoError(s.T(), err)
+	require.True(s.T(), results[0].Valid)
+	require.ErrorIs(s.T(), results[1].Error, auth_manager.ErrTokenRevoked)
+	require.ErrorIs(s.T(), results[2].Error, auth_manager.ErrInvalidToken)
+	require.ErrorIs(s.T(), results[3].Error, auth_manager.ErrInvalidToken)
+
+	// Only a failure to check the tokens fails the call
+	closedClient := redis.NewClient(redisClient.Options())
+	require.NoError(s.T(), closedClient.Close())
+	manager := auth_manager.NewAuthManager(closedClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+
+	_, err = manager.ValidateBatch(ctx, []string{valid}, auth_manager.VerifyEmail)
+	require.Error(s.T(), err)
+
+	_, err = manager.ValidateBatch(ctx, []string{accessToken}, auth_manager.AccessToken)
+	require.Error(s.T(), err)
+}
@@ -0,0 +1,108 @@
+package auth_manager_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+)
+
+func newBenchmarkAuthManager() auth_manager.AuthManager {
+	return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+}
+
+func BenchmarkGenerateAccessToken(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.GenerateAccessToken(ctx, "user-uuid", time.Minute); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeAccessToken(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+
+	token, err := authManager.GenerateAccessToken(ctx, "user-uuid", time.Minute)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.DecodeAccessToken(ctx, token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGeneratePlainToken(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+	payload := &auth_manager.TokenPayload{
+		UUID:      "user-uuid",
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Minute); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodePlainToken(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+
+	token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      "user-uuid",
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeAccessTokenJunk(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.DecodeAccessToken(ctx, "not a token at all"); err == nil {
+			b.Fatal("junk input was accepted")
+		}
+	}
+}
+
+func BenchmarkDecodePlainTokenJunk(b *testing.B) {
+	ctx := context.TODO()
+	authManager := newBenchmarkAuthManager()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := authManager.DecodePlainToken(ctx, "not a token at all", auth_manager.VerifyEmail); err == nil {
+			b.Fatal("junk input was accepted")
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package auth_manager
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is used when AuthManagerOpts.CacheSize is set without a CacheTTL.
+const defaultCacheTTL = time.Second * 5
+
+// userEvictionBuckets spreads the users over the eviction versions of evictUser, so evicting a user only keeps
+// decodes of the users sharing its bucket from being cached.
+const userEvictionBuckets = 256
+
+type cacheEntry struct {
+	key       string
+	uuid      string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// cacheVersion is the state of the evictions when a decode started, see decodeCache.version.
+type cacheVersion struct {
+	evictions     uint64
+	userEvictions uint64
+}
+
+// decodeCache is an in-process LRU cache of decode results keyed by token hash.
+// A nil *decodeCache is a disabled cache, so callers don't have to check whether it is enabled.
+type decodeCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+
+	// users indexes the entries by the uuid of their token, so evictUser doesn't walk the whole cache.
+	users map[string]map[*list.Element]struct{}
+
+	// evictions counts the evictions, so a decode which read Redis before a concurrent destroy doesn't cache
+	// the token again after it was evicted.
+	evictions uint64
+
+	// userEvictions counts the calls of evictUser, and userEvicted holds the count of the last one per bucket
+	// of users, so evicting a user doesn't keep the decodes of every other user from being cached.
+	userEvictions uint64
+	userEvicted   [userEvictionBuckets]uint64
+}
+
+func newDecodeCache(capacity int, ttl time.Duration) *decodeCache {
+	if capacity <= 0 {
+		return nil
+	}
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	return &decodeCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		users:    make(map[string]map[*list.Element]struct{}),
+	}
+}
+
+func (c *decodeCache) get(token string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	key := HashToken(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// version returns the version of the cache to pass to set, taken before the token is read from Redis.
+func (c *decodeCache) version() cacheVersion {
+	if c == nil {
+		return cacheVersion{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return cacheVersion{evictions: c.evictions, userEvictions: c.userEvictions}
+}
+
+// set caches the value of a token of the user for the cache TTL, but never beyond validUntil which is the
+// expiration of the token itself. Nothing is cached when an entry, or an entry of a user in the same bucket,
+// was evicted since the version was taken, the value may be of a destroyed token.
+func (c *decodeCache) set(token string, uuid string, value interface{}, validUntil time.Time, version cacheVersion) {
+	if c == nil {
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	if validUntil.Before(expiresAt) {
+		expiresAt = validUntil
+	}
+	if !expiresAt.After(time.Now()) {
+		return
+	}
+
+	key := HashToken(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.evictions != version.evictions || c.userEvicted[userEvictionBucket(uuid)] > version.userEvictions {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+
+	element := c.order.PushFront(&cacheEntry{key, uuid, value, expiresAt})
+	c.entries[key] = element
+	if c.users[uuid] == nil {
+		c.users[uuid] = make(map[*list.Element]struct{})
+	}
+	c.users[uuid][element] = struct{}{}
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *decodeCache) evict(token string) {
+	c.evictHash(HashToken(token))
+}
+
+// evictHash removes the entry of the token with the HashToken digest.
+func (c *decodeCache) evictHash(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictions++
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// evictFunc removes every entry whose value matches. It walks the whole cache and is meant for rare revocations.
+func (c *decodeCache) evictFunc(match func(value interface{}) bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictions++
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if match(element.Value.(*cacheEntry).value) {
+			c.removeElement(element)
+		}
+		element = next
+	}
+}
+
+// evictUser removes the entries of the user whose value matches, or all of them when match is nil. Only the
+// entries of the user are walked, so it is cheap enough to run on every login.
+func (c *decodeCache) evictUser(uuid string, match func(value interface{}) bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.userEvictions++
+	c.userEvicted[userEvictionBucket(uuid)] = c.userEvictions
+	for element := range c.users[uuid] {
+		if match == nil || match(element.Value.(*cacheEntry).value) {
+			c.removeElement(element)
+		}
+	}
+}
+
+func userEvictionBucket(uuid string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(uuid))
+	return h.Sum32() % userEvictionBuckets
+}
+
+func (c *decodeCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+
+	entry := element.Value.(*cacheEntry)
+	delete(c.entries, entry.key)
+	if userEntries := c.users[entry.uuid]; userEntries != nil {
+		delete(userEntries, element)
+		if len(userEntries) == 0 {
+			delete(c.users, entry.uuid)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package auth_manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeCacheEvictUser(t *testing.T) {
+	cache := newDecodeCache(10, time.Minute)
+	validUntil := time.Now().Add(time.Minute)
+
+	cache.set("token-a1", "user-a", "a1", validUntil, cache.version())
+	cache.set("token-a2", "user-a", "a2", validUntil, cache.version())
+	cache.set("token-b", "user-b", "b", validUntil, cache.version())
+
+	version := cache.version()
+	cache.evictUser("user-a", func(value interface{}) bool { return value != "a2" })
+
+	if _, ok := cache.get("token-a1"); ok {
+		t.Fatal("matching entry of the user was not evicted")
+	}
+	if _, ok := cache.get("token-a2"); !ok {
+		t.Fatal("entry of the user which doesn't match was evicted")
+	}
+	if _, ok := cache.get("token-b"); !ok {
+		t.Fatal("entry of another user was evicted")
+	}
+
+	// A decode which started before the eviction caches the tokens of other users only
+	cache.set("token-a3", "user-a", "a3", validUntil, version)
+	if _, ok := cache.get("token-a3"); ok {
+		t.Fatal("token of the evicted user was cached with a stale version")
+	}
+	other := "user-c"
+	for userEvictionBucket(other) == userEvictionBucket("user-a") {
+		other += "c"
+	}
+	cache.set("token-c", other, "c", validUntil, version)
+	if _, ok := cache.get("token-c"); !ok {
+		t.Fatal("token of another user was not cached")
+	}
+
+	cache.evictUser("user-a", nil)
+	if _, ok := cache.get("token-a2"); ok {
+		t.Fatal("entry of the user was not evicted")
+	}
+	if len(cache.users) != 2 {
+		t.Fatalf("index holds %d users, want 2", len(cache.users))
+	}
+}
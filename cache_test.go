@@ -0,0 +1,334 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// countingHook counts the commands sent to Redis.
+type countingHook struct {
+	commands atomic.Int64
+}
+
+func (h *countingHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	h.commands.Add(1)
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *countingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.commands.Add(int64(len(cmds)))
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeCache() {
+	ctx := context.TODO()
+
+	hook := &countingHook{}
+	countingClient := redis.NewClient(redisClient.Options())
+	countingClient.AddHook(hook)
+	defer countingClient.Close()
+
+	authManager := auth_manager.NewAuthManager(countingClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		CacheSize:  16,
+		CacheTTL:   time.Minute,
+	})
+
+	// Plain tokens
+	token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	commands := hook.commands.Load()
+	decoded, err := authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), decoded)
+	require.Equal(s.T(), commands, hook.commands.Load())
+
+	err = authManager.DestroyPlainToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	// Access tokens
+	accessToken, err := authManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := authManager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	commands = hook.commands.Load()
+	_, err = authManager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), commands, hook.commands.Load())
+
+	err = authManager.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodeAccessToken(ctx, accessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeCacheNeverOutlivesToken() {
+	ctx := context.TODO()
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		CacheSize:  16,
+		CacheTTL:   time.Minute,
+	})
+
+	token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Millisecond*300)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	time.Sleep(time.Millisecond * 500)
+
+	_, err = authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
+
+func (s *AuthManagerTestSuite) Test_InvalidationChannel() {
+	ctx := context.TODO()
+	channel := "invalidations:" + uuid.NewString()
+
+	newManager := func() auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:          "private-key",
+			CacheSize:           10,
+			CacheTTL:            time.Minute,
+			InvalidationChannel: channel,
+		})
+	}
+	first, second := newManager(), newManager()
+
+	subscription, err := second.SubscribeInvalidations(ctx)
+	require.NoError(s.T(), err)
+	defer subscription.Close()
+
+	accessToken, err := first.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+	plainToken, err := first.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Cached by the second instance
+	claims, err := second.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	_, err = second.DecodePlainToken(ctx, plainToken, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	err = first.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+	err = first.DestroyPlainToken(ctx, plainToken)
+	require.NoError(s.T(), err)
+
+	require.Eventually(s.T(), func() bool {
+		_, accessErr := second.DecodeAccessToken(ctx, accessToken)
+		_, plainErr := second.DecodePlainToken(ctx, plainToken, auth_manager.VerifyEmail)
+		return errors.Is(accessErr, auth_manager.ErrTokenRevoked) && errors.Is(plainErr, auth_manager.ErrTokenExpired)
+	}, time.Second*2, time.Millisecond*10)
+
+	_, err = s.authManager.SubscribeInvalidations(ctx)
+	require.ErrorIs(s.T(), err, auth_manager.ErrNoInvalidationChannel)
+}
+
+func (s *AuthManagerTestSuite) Test_InvalidationChannelSessions() {
+	ctx := context.TODO()
+	channel := "invalidations:" + uuid.NewString()
+	userUUID := uuid.NewString()
+
+	newManager := func(opts auth_manager.AuthManagerOpts) auth_manager.AuthManager {
+		opts.PrivateKey = "private-key"
+		opts.CacheSize = 10
+		opts.CacheTTL = time.Minute
+		opts.InvalidationChannel = channel
+		return auth_manager.NewAuthManager(redisClient, opts)
+	}
+	sessionOpts := auth_manager.AuthManagerOpts{VerifySession: true}
+	first, second := newManager(sessionOpts), newManager(sessionOpts)
+
+	subscription, err := second.SubscribeInvalidations(ctx)
+	require.NoError(s.T(), err)
+	defer subscription.Close()
+
+	sessionID, err := first.CreateSession(ctx, userUUID, time.Hour)
+	require.NoError(s.T(), err)
+	sessionToken, err := first.GenerateSessionAccessToken(ctx, sessionID, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Cached by the second instance
+	_, err = second.DecodeAccessToken(ctx, sessionToken)
+	require.NoError(s.T(), err)
+
+	// A destroyed session is published
+	require.NoError(s.T(), first.DestroySession(ctx, sessionID))
+	require.Eventually(s.T(), func() bool {
+		_, err := second.DecodeAccessToken(ctx, sessionToken)
+		return errors.Is(err, auth_manager.ErrSessionExpired)
+	}, time.Second*2, time.Millisecond*10)
+
+	// So is a token superseded by a newer login
+	singleSessionOpts := auth_manager.AuthManagerOpts{SingleSession: true}
+	first, second = newManager(singleSessionOpts), newManager(singleSessionOpts)
+
+	subscription, err = second.SubscribeInvalidations(ctx)
+	require.NoError(s.T(), err)
+	defer subscription.Close()
+
+	supersededToken, err := first.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = second.DecodeAccessToken(ctx, supersededToken)
+	require.NoError(s.T(), err)
+
+	currentToken, err := first.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	require.Eventually(s.T(), func() bool {
+		_, err := second.DecodeAccessToken(ctx, supersededToken)
+		return errors.Is(err, auth_manager.ErrSessionSuperseded)
+	}, time.Second*2, time.Millisecond*10)
+
+	_, err = second.DecodeAccessToken(ctx, currentToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_ConcurrentDecodeAndDestroy() {
+	ctx := context.TODO()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		CacheSize:  16,
+		CacheTTL:   time.Minute,
+	})
+	userUUID := uuid.NewString()
+
+	for i := 0; i < 20; i++ {
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		var destroyed atomic.Bool
+		outcomes := make(chan error, 400)
+		done := make(chan struct{})
+		for j := 0; j < 4; j++ {
+			go func() {
+				defer func() { done <- struct{}{} }()
+
+				for k := 0; k < 100; k++ {
+					destroyedBefore := destroyed.Load()
+
+					claims, err := manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+					if err == nil && claims.UUID != userUUID {
+						err = errors.New("decoded the claims of another token")
+					}
+					if err == nil && destroyedBefore {
+						err = errors.New("decoded a token which was already destroyed")
+					}
+					if err != nil && !errors.Is(err, auth_manager.ErrTokenExpired) {
+						outcomes <- err
+					}
+				}
+			}()
+		}
+
+		err = manager.DestroyPlainToken(ctx, token)
+		require.NoError(s.T(), err)
+		destroyed.Store(true)
+
+		for j := 0; j < 4; j++ {
+			<-done
+		}
+		close(outcomes)
+		for err := range outcomes {
+			require.NoError(s.T(), err)
+		}
+
+		// A decode which read the token before its destroy didn't cache it again
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+	}
+
+	// The destroy runs in between the read of a decode and its write to the cache
+	hook := &afterReadHook{}
+	hookedClient := redis.NewClient(redisClient.Options())
+	hookedClient.AddHook(hook)
+	defer hookedClient.Close()
+
+	manager = auth_manager.NewAuthManager(hookedClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		CacheSize:  16,
+		CacheTTL:   time.Minute,
+	})
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	hook.afterRead = func() {
+		require.NoError(s.T(), manager.DestroyPlainToken(ctx, token))
+	}
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
+
+// afterReadHook runs afterRead once, after the first pipeline which was sent to Redis with it set.
+type afterReadHook struct {
+	afterRead func()
+}
+
+func (h *afterReadHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *afterReadHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *afterReadHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *afterReadHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	if afterRead := h.afterRead; afterRead != nil {
+		h.afterRead = nil
+		afterRead()
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package auth_manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// CodeChallenge returns the challenge of the code verifier, BASE64URL(SHA256(verifier)) like the S256 method of
+// PKCE. It is set as the CodeChallenge of the payload of a plain token, so only the hash of the verifier is stored
+// and the verifier itself stays with the client which started the flow.
+func CodeChallenge(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// DecodeTokenWithVerifier decodes a token of the type like DecodeTokenForPurpose and rejects it with
+// ErrChallengeMismatch unless the challenge of the verifier is the CodeChallenge it was issued with. A token
+// issued without a challenge is always rejected, so a link leaked on its own can't be used.
+func (t *authManager) DecodeTokenWithVerifier(ctx context.Context, token string, tokenType TokenType, verifier string) (*TokenPayload, error) {
+	claims, err := t.decodeTokenOfType(ctx, token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := CodeChallenge(verifier)
+	if claims.CodeChallenge == "" || subtle.ConstantTimeCompare([]byte(claims.CodeChallenge), []byte(challenge)) != 1 {
+		return nil, t.disclose(ErrChallengeMismatch)
+	}
+
+	return claims, nil
+}
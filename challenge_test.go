@@ -0,0 +1,55 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenWithVerifier() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	// The S256 example of RFC 7636
+	require.Equal(s.T(), "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", auth_manager.CodeChallenge(verifier))
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:          userUUID,
+		CreatedAt:     time.Now(),
+		CodeChallenge: auth_manager.CodeChallenge(verifier),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Only the challenge is stored
+	stored, err := redisClient.Get(ctx, token).Result()
+	require.NoError(s.T(), err)
+	require.NotContains(s.T(), stored, verifier)
+
+	claims, err := s.authManager.DecodeTokenWithVerifier(ctx, token, auth_manager.VerifyEmail, verifier)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	_, err = s.authManager.DecodeTokenWithVerifier(ctx, token, auth_manager.VerifyEmail, "another-verifier")
+	require.ErrorIs(s.T(), err, auth_manager.ErrChallengeMismatch)
+
+	// A token without a challenge can't be decoded with a verifier
+	unboundToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenWithVerifier(ctx, unboundToken, auth_manager.VerifyEmail, verifier)
+	require.ErrorIs(s.T(), err, auth_manager.ErrChallengeMismatch)
+	_, err = s.authManager.DecodeTokenWithVerifier(ctx, unboundToken, auth_manager.VerifyEmail, "")
+	require.ErrorIs(s.T(), err, auth_manager.ErrChallengeMismatch)
+
+	// The token is validated first
+	_, err = s.authManager.DecodeTokenWithVerifier(ctx, token, auth_manager.ResetPassword, verifier)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+}
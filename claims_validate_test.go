@@ -0,0 +1,70 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ClaimsValidate() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	now := time.Now()
+
+	validPayload := auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.AccessToken,
+		CreatedAt: now,
+	}
+	require.NoError(s.T(), validPayload.Validate())
+
+	for name, tc := range map[string]struct {
+		payload auth_manager.TokenPayload
+		err     error
+	}{
+		"empty uuid":         {auth_manager.TokenPayload{TokenType: auth_manager.VerifyEmail, CreatedAt: now}, auth_manager.ErrEmptyUUID},
+		"unknown token type": {auth_manager.TokenPayload{UUID: userUUID, TokenType: 42, CreatedAt: now}, auth_manager.ErrInvalidTokenType},
+		"zero created at":    {auth_manager.TokenPayload{UUID: userUUID, TokenType: auth_manager.VerifyEmail}, auth_manager.ErrInvalidCreatedAt},
+		"future created at":  {auth_manager.TokenPayload{UUID: userUUID, TokenType: auth_manager.VerifyEmail, CreatedAt: now.Add(time.Hour)}, auth_manager.ErrInvalidCreatedAt},
+	} {
+		require.ErrorIs(s.T(), tc.payload.Validate(), tc.err, name)
+	}
+
+	validClaims := auth_manager.AccessTokenClaims{
+		Payload:   validPayload,
+		Subject:   userUUID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Minute),
+	}
+	require.NoError(s.T(), validClaims.Validate())
+
+	for name, tc := range map[string]struct {
+		modify func(claims *auth_manager.AccessTokenClaims)
+		err    error
+	}{
+		"invalid payload":   {func(claims *auth_manager.AccessTokenClaims) { claims.Payload.CreatedAt = time.Time{} }, auth_manager.ErrInvalidCreatedAt},
+		"plain token type":  {func(claims *auth_manager.AccessTokenClaims) { claims.Payload.TokenType = auth_manager.VerifyEmail }, auth_manager.ErrInvalidTokenType},
+		"foreign subject":   {func(claims *auth_manager.AccessTokenClaims) { claims.Subject = uuid.NewString() }, auth_manager.ErrSubjectMismatch},
+		"no expiry":         {func(claims *auth_manager.AccessTokenClaims) { claims.ExpiresAt = time.Time{} }, auth_manager.ErrNoExpiration},
+		"expiry before iat": {func(claims *auth_manager.AccessTokenClaims) { claims.ExpiresAt = now.Add(-time.Minute) }, auth_manager.ErrInvalidExpiry},
+	} {
+		claims := validClaims
+		tc.modify(&claims)
+		require.ErrorIs(s.T(), claims.Validate(), tc.err, name)
+	}
+
+	// Generated tokens are validated before they are stored
+	_, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: now.Add(time.Hour),
+	}, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidCreatedAt)
+
+	activeTokens, err := s.authManager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), activeTokens)
+}
@@ -0,0 +1,23 @@
+package auth_manager
+
+import (
+	"context"
+	"fmt"
+)
+
+// ClaimsValidator is a custom validation rule for the claims of decoded tokens, see AuthManagerOpts.ClaimsValidator.
+type ClaimsValidator func(ctx context.Context, claims *TokenPayload) error
+
+// validateClaims runs the ClaimsValidator. Its error is returned wrapped, so both errors.Is(err, ErrInvalidToken)
+// and errors.Is against the validator's own error hold.
+func (t *authManager) validateClaims(ctx context.Context, claims *TokenPayload) error {
+	if t.opts.ClaimsValidator == nil {
+		return nil
+	}
+
+	if err := t.opts.ClaimsValidator(ctx, claims); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ClaimsValidator() {
+	ctx := context.TODO()
+	suspendedUUID := uuid.NewString()
+	activeUUID := uuid.NewString()
+	errSuspended := errors.New("user is suspended")
+
+	newManager := func(validator auth_manager.ClaimsValidator) auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:      "private-key",
+			ClaimsValidator: validator,
+		})
+	}
+	rejectSuspended := newManager(func(_ context.Context, claims *auth_manager.TokenPayload) error {
+		if claims.UUID == suspendedUUID {
+			return errSuspended
+		}
+		return nil
+	})
+	allowAll := newManager(func(context.Context, *auth_manager.TokenPayload) error {
+		return nil
+	})
+
+	suspendedAccessToken, err := rejectSuspended.GenerateAccessToken(ctx, suspendedUUID, time.Minute)
+	require.NoError(s.T(), err)
+	activeAccessToken, err := rejectSuspended.GenerateAccessToken(ctx, activeUUID, time.Minute)
+	require.NoError(s.T(), err)
+	suspendedPlainToken, err := rejectSuspended.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      suspendedUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Rejected in every decode path, with both errors
+	_, err = rejectSuspended.DecodeAccessToken(ctx, suspendedAccessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+	require.ErrorIs(s.T(), err, errSuspended)
+
+	_, err = rejectSuspended.DecodePlainToken(ctx, suspendedPlainToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, errSuspended)
+
+	_, err = rejectSuspended.DecodeTokenAllowing(ctx, suspendedPlainToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, errSuspended)
+
+	_, err = rejectSuspended.DecodeTokenAllowing(ctx, suspendedAccessToken, auth_manager.AccessToken)
+	require.ErrorIs(s.T(), err, errSuspended)
+
+	_, err = rejectSuspended.DecodeAccessToken(ctx, activeAccessToken)
+	require.NoError(s.T(), err)
+
+	// A validator which allows everything doesn't change anything
+	_, err = allowAll.DecodeAccessToken(ctx, suspendedAccessToken)
+	require.NoError(s.T(), err)
+
+	_, err = allowAll.DecodePlainToken(ctx, suspendedPlainToken, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,84 @@
+package auth_manager
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec serializes the values stored in Redis. Every instance sharing a Redis must use the same codec,
+// values written by one codec can't be read by another.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec is the default codec.
+	JSONCodec Codec = jsonCodec{}
+
+	// MessagePackCodec produces smaller values and is faster to decode than JSONCodec.
+	MessagePackCodec Codec = messagePackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type messagePackCodec struct{}
+
+// Marshal encodes with the json struct tags so both codecs share the same field names.
+func (messagePackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (messagePackCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+// payloadSchema is the version of the stored claims of plain tokens written by this version of the package.
+// Bump it when a field of TokenPayload changes meaning; added fields only need a sensible zero value.
+const payloadSchema = 2
+
+// storedPayload is the value stored for a plain token. The claims are inlined next to the schema, so values
+// written before the schema existed, version 1, decode as they are.
+type storedPayload struct {
+	Schema int `json:"schema,omitempty"`
+	TokenPayload
+}
+
+// encodePayload encodes the claims of a plain token to be stored, stamped with the current schema.
+func (t *authManager) encodePayload(claims *TokenPayload) ([]byte, error) {
+	return t.codec.Marshal(storedPayload{Schema: payloadSchema, TokenPayload: *claims})
+}
+
+// decodePayload decodes the stored claims of a plain token. Values of older schemas lack the fields added since,
+// which keep their zero value: no Purpose, Epoch or Meta. Fields of newer schemas are ignored, so a rollback
+// doesn't invalidate the tokens issued in the meantime.
+func (t *authManager) decodePayload(value string) (*TokenPayload, error) {
+	stored := &storedPayload{}
+
+	err := t.codec.Unmarshal([]byte(value), &stored)
+	if err != nil || stored == nil {
+		return nil, ErrCorruptedEntry
+	}
+
+	return &stored.TokenPayload, nil
+}
@@ -0,0 +1,133 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_Codecs() {
+	ctx := context.TODO()
+	payload := &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now().Truncate(time.Millisecond),
+		Meta: &auth_manager.TokenMeta{
+			IPAddress: "192.168.1.1",
+			UserAgent: "Mozilla/5.0",
+		},
+	}
+
+	sizes := map[string]int{}
+	for name, codec := range map[string]auth_manager.Codec{
+		"json":        auth_manager.JSONCodec,
+		"messagepack": auth_manager.MessagePackCodec,
+	} {
+		authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey: "private-key",
+			Codec:      codec,
+		})
+
+		token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Minute)
+		require.NoError(s.T(), err, name)
+
+		decoded, err := authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err, name)
+		require.Equal(s.T(), payload.UUID, decoded.UUID, name)
+		require.True(s.T(), payload.CreatedAt.Equal(decoded.CreatedAt), name)
+		require.Equal(s.T(), payload.Meta, decoded.Meta, name)
+
+		stored, err := redisClient.Get(ctx, token).Result()
+		require.NoError(s.T(), err, name)
+		sizes[name] = len(stored)
+
+		refreshPayload := &auth_manager.RefreshTokenPayload{IPAddress: "ip-address", UserAgent: "user-agent"}
+		refreshToken, err := authManager.GenerateRefreshToken(ctx, payload.UUID, refreshPayload, time.Minute)
+		require.NoError(s.T(), err, name)
+
+		decodedRefresh, err := authManager.DecodeRefreshToken(ctx, payload.UUID, refreshToken)
+		require.NoError(s.T(), err, name)
+		require.Equal(s.T(), refreshPayload, decodedRefresh, name)
+	}
+
+	require.Less(s.T(), sizes["messagepack"], sizes["json"])
+}
+
+// v1TokenPayload is the shape of the stored claims before Purpose, Epoch and the schema were added.
+type v1TokenPayload struct {
+	UUID      string                  `json:"uuid"`
+	CreatedAt time.Time               `json:"createdAt"`
+	TokenType auth_manager.TokenType  `json:"tokenType"`
+	Meta      *auth_manager.TokenMeta `json:"meta,omitempty"`
+}
+
+func (s *AuthManagerTestSuite) Test_PayloadSchema() {
+	ctx := context.TODO()
+
+	for name, codec := range map[string]auth_manager.Codec{
+		"json":        auth_manager.JSONCodec,
+		"messagepack": auth_manager.MessagePackCodec,
+	} {
+		authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey: "private-key",
+			Codec:      codec,
+		})
+		userUUID := uuid.NewString()
+
+		token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+			Purpose:   "signup",
+		}, time.Minute)
+		require.NoError(s.T(), err, name)
+
+		// The current value still decodes with the shape of version 1
+		stored, err := redisClient.Get(ctx, token).Result()
+		require.NoError(s.T(), err, name)
+
+		var v1Claims v1TokenPayload
+		err = codec.Unmarshal([]byte(stored), &v1Claims)
+		require.NoError(s.T(), err, name)
+		require.Equal(s.T(), userUUID, v1Claims.UUID, name)
+		require.Equal(s.T(), auth_manager.VerifyEmail, v1Claims.TokenType, name)
+
+		// A value written in version 1 decodes with the fields added since left empty
+		v1Value, err := codec.Marshal(v1TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+			TokenType: auth_manager.VerifyEmail,
+		})
+		require.NoError(s.T(), err, name)
+		err = redisClient.Set(ctx, token, v1Value, time.Minute).Err()
+		require.NoError(s.T(), err, name)
+
+		decoded, err := authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err, name)
+		require.Equal(s.T(), userUUID, decoded.UUID, name)
+		require.Empty(s.T(), decoded.Purpose, name)
+		require.Zero(s.T(), decoded.Epoch, name)
+		require.Nil(s.T(), decoded.Meta, name)
+
+		// A value of a newer schema decodes, its unknown fields are ignored
+		futureValue, err := codec.Marshal(map[string]interface{}{
+			"schema":    3,
+			"uuid":      userUUID,
+			"createdAt": time.Now(),
+			"tokenType": auth_manager.VerifyEmail,
+			"purpose":   "signup",
+			"scopes":    []string{"email"},
+		})
+		require.NoError(s.T(), err, name)
+		err = redisClient.Set(ctx, token, futureValue, time.Minute).Err()
+		require.NoError(s.T(), err, name)
+
+		decoded, err = authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err, name)
+		require.Equal(s.T(), userUUID, decoded.UUID, name)
+		require.Equal(s.T(), "signup", decoded.Purpose, name)
+	}
+}
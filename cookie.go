@@ -0,0 +1,83 @@
+package auth_manager
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+const DefaultTokenCookieName = "auth_token"
+
+type CookieOpts struct {
+	// Name of the cookie, defaults to DefaultTokenCookieName.
+	Name   string
+	Path   string
+	Domain string
+
+	// ExpiresIn should be the expiration of the token, the cookie's Max-Age is tied to it. It is rounded up to
+	// whole seconds, the precision of Max-Age. Zero or less sets a session cookie, dropped when the browser closes.
+	ExpiresIn time.Duration
+
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// Insecure drops the Secure attribute, only meant for local development over plain http.
+	Insecure bool
+}
+
+// SetTokenCookie stores the token in an HttpOnly cookie which expires along with the token.
+func SetTokenCookie(w http.ResponseWriter, token string, opts CookieOpts) {
+	name := opts.Name
+	if name == "" {
+		name = DefaultTokenCookieName
+	}
+
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+
+	sameSite := opts.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     path,
+		Domain:   opts.Domain,
+		HttpOnly: true,
+		Secure:   !opts.Insecure,
+		SameSite: sameSite,
+	}
+	if opts.ExpiresIn > 0 {
+		// A sub-second lifetime would truncate to a Max-Age of 0, which omits the attribute.
+		expiresIn := opts.ExpiresIn.Truncate(time.Second)
+		if expiresIn < opts.ExpiresIn {
+			expiresIn += time.Second
+		}
+
+		cookie.MaxAge = int(expiresIn / time.Second)
+		cookie.Expires = time.Now().Add(expiresIn)
+	}
+
+	http.SetCookie(w, cookie)
+}
+
+// ReadTokenCookie reads back a token stored by SetTokenCookie. An empty name reads DefaultTokenCookieName.
+func ReadTokenCookie(r *http.Request, name string) (string, error) {
+	if name == "" {
+		name = DefaultTokenCookieName
+	}
+
+	cookie, err := r.Cookie(name)
+	if errors.Is(err, http.ErrNoCookie) || (err == nil && cookie.Value == "") {
+		return "", ErrTokenNotProvided
+	}
+	if err != nil {
+		return "", ErrMalformedAuthorization
+	}
+
+	return cookie.Value, nil
+}
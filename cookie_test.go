@@ -0,0 +1,76 @@
+package auth_manager_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_TokenCookie() {
+	recorder := httptest.NewRecorder()
+	auth_manager.SetTokenCookie(recorder, "token-value", auth_manager.CookieOpts{
+		ExpiresIn: time.Hour,
+	})
+
+	cookies := recorder.Result().Cookies()
+	require.Len(s.T(), cookies, 1)
+
+	cookie := cookies[0]
+	require.Equal(s.T(), auth_manager.DefaultTokenCookieName, cookie.Name)
+	require.Equal(s.T(), "token-value", cookie.Value)
+	require.Equal(s.T(), "/", cookie.Path)
+	require.Equal(s.T(), 3600, cookie.MaxAge)
+	require.True(s.T(), cookie.HttpOnly)
+	require.True(s.T(), cookie.Secure)
+	require.Equal(s.T(), http.SameSiteLaxMode, cookie.SameSite)
+	require.WithinDuration(s.T(), time.Now().Add(time.Hour), cookie.Expires, time.Minute)
+
+	// Round-trip through a request
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	token, err := auth_manager.ReadTokenCookie(r, "")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "token-value", token)
+
+	// Custom attributes
+	recorder = httptest.NewRecorder()
+	auth_manager.SetTokenCookie(recorder, "token-value", auth_manager.CookieOpts{
+		Name:      "refresh_token",
+		Path:      "/auth",
+		ExpiresIn: time.Minute,
+		SameSite:  http.SameSiteStrictMode,
+		Insecure:  true,
+	})
+
+	cookie = recorder.Result().Cookies()[0]
+	require.Equal(s.T(), "refresh_token", cookie.Name)
+	require.Equal(s.T(), "/auth", cookie.Path)
+	require.Equal(s.T(), 60, cookie.MaxAge)
+	require.False(s.T(), cookie.Secure)
+	require.Equal(s.T(), http.SameSiteStrictMode, cookie.SameSite)
+
+	_, err = auth_manager.ReadTokenCookie(httptest.NewRequest(http.MethodGet, "/", nil), "refresh_token")
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenNotProvided)
+
+	// Without a lifetime it is a session cookie
+	recorder = httptest.NewRecorder()
+	auth_manager.SetTokenCookie(recorder, "token-value", auth_manager.CookieOpts{})
+
+	header := recorder.Header().Get("Set-Cookie")
+	require.NotContains(s.T(), header, "Max-Age")
+	require.NotContains(s.T(), header, "Expires")
+
+	// A sub-second lifetime is rounded up instead of expiring right away
+	recorder = httptest.NewRecorder()
+	auth_manager.SetTokenCookie(recorder, "token-value", auth_manager.CookieOpts{
+		ExpiresIn: 500 * time.Millisecond,
+	})
+
+	cookie = recorder.Result().Cookies()[0]
+	require.Equal(s.T(), 1, cookie.MaxAge)
+	require.True(s.T(), cookie.Expires.After(time.Now()))
+}
@@ -0,0 +1,222 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ValidationCheck is one of the checks a token goes through when it is decoded.
+type ValidationCheck string
+
+const (
+	// CheckFormat checks the size of the token and that it is shaped like a jwt or a plain token.
+	CheckFormat ValidationCheck = "format"
+	// CheckSignature checks the signing method and the signature of a jwt.
+	CheckSignature ValidationCheck = "signature"
+	// CheckExpiry checks the exp claim of a jwt, and that it is within MaxExpiryHorizon.
+	CheckExpiry ValidationCheck = "expiry"
+	// CheckNotBefore checks the nbf claim of a jwt.
+	CheckNotBefore ValidationCheck = "not_before"
+	// CheckTokenType checks that the token is of the expected type.
+	CheckTokenType ValidationCheck = "token_type"
+	// CheckAge checks MaxTokenAge or PlainTokenMaxAge.
+	CheckAge ValidationCheck = "age"
+	// CheckRevoked checks that the token was not revoked.
+	CheckRevoked ValidationCheck = "revoked"
+	// CheckFound checks that the token is still stored in Redis.
+	CheckFound ValidationCheck = "found"
+	// CheckCurrent checks the user epoch and, for access tokens, VerifySession and SingleSession.
+	CheckCurrent ValidationCheck = "current"
+	// CheckClaims runs the ClaimsValidator.
+	CheckClaims ValidationCheck = "claims"
+)
+
+// CheckStatus is the outcome of a check.
+type CheckStatus int
+
+const (
+	CheckPassed CheckStatus = iota
+	CheckFailed
+	// CheckSkipped is the status of the checks after a failed one, and of every check when the decode failed for
+	// another reason than the token, e.g. Redis being unreachable.
+	CheckSkipped
+)
+
+var checkStatusNames = map[CheckStatus]string{
+	CheckPassed:  "passed",
+	CheckFailed:  "failed",
+	CheckSkipped: "skipped",
+}
+
+func (s CheckStatus) String() string {
+	return checkStatusNames[s]
+}
+
+// CheckResult is the outcome of a check. Err is the error the decode returned when the check failed.
+type CheckResult struct {
+	Check  ValidationCheck
+	Status CheckStatus
+	Err    error
+}
+
+// ValidationReport lists the checks of a decode in the order they run.
+type ValidationReport struct {
+	Checks []CheckResult
+}
+
+// Failed returns the check which rejected the token, if any.
+func (r *ValidationReport) Failed() (CheckResult, bool) {
+	for _, result := range r.Checks {
+		if result.Status == CheckFailed {
+			return result, true
+		}
+	}
+
+	return CheckResult{}, false
+}
+
+// DecodeTokenDetailed decodes the token like DecodeAccessToken or DecodePlainToken, and reports which check
+// rejected it, for debugging integrations and support. The error is the one the decode method returns; on success
+// every check of the report passed.
+func (t *authManager) DecodeTokenDetailed(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, *ValidationReport, error) {
+	token = trimToken(token)
+	signed := tokenType == AccessToken || t.stateless(tokenType)
+
+	var claims *TokenPayload
+	var err error
+	if tokenType == AccessToken {
+		var accessClaims *AccessTokenClaims
+		accessClaims, err = t.decodeAccessToken(ctx, token)
+		if err == nil {
+			claims = &accessClaims.Payload
+		}
+	} else {
+		claims, err = t.decodePlainToken(ctx, token, tokenType)
+	}
+
+	var failed ValidationCheck
+	if err == nil {
+		err = t.validateClaims(ctx, claims)
+		failed = CheckClaims
+	} else {
+		failed = t.failedCheck(token, signed, err)
+	}
+
+	report := t.newValidationReport(tokenType, signed)
+	report.record(failed, err)
+	if err != nil {
+		return nil, report, err
+	}
+
+	t.observeTokenAge(claims)
+
+	return claims, report, nil
+}
+
+// newValidationReport lists the checks of the decode path of the token type.
+func (t *authManager) newValidationReport(tokenType TokenType, signed bool) *ValidationReport {
+	var checks []ValidationCheck
+	switch {
+	case tokenType == AccessToken:
+		checks = []ValidationCheck{CheckFormat, CheckSignature, CheckExpiry, CheckNotBefore, CheckTokenType, CheckAge, CheckRevoked, CheckCurrent}
+	case signed:
+		checks = []ValidationCheck{CheckFormat, CheckSignature, CheckExpiry, CheckNotBefore, CheckTokenType, CheckCurrent}
+		if t.opts.TrackStatelessTokens && t.opts.AcceptOnRedisMiss {
+			checks = append(checks, CheckRevoked)
+		} else if t.opts.TrackStatelessTokens {
+			checks = append(checks, CheckFound)
+		}
+	default:
+		// A missing token is reported as revoked when it has a tombstone, see SoftDeleteGracePeriod.
+		checks = []ValidationCheck{CheckFormat, CheckRevoked, CheckFound, CheckAge, CheckCurrent, CheckTokenType}
+	}
+	checks = append(checks, CheckClaims)
+
+	report := &ValidationReport{Checks: make([]CheckResult, len(checks))}
+	for i, check := range checks {
+		report.Checks[i] = CheckResult{Check: check, Status: CheckPassed}
+	}
+
+	return report
+}
+
+// record marks the failed check and skips the ones after it. Without a known failed check every check is
+// skipped when the decode failed.
+func (r *ValidationReport) record(failed ValidationCheck, err error) {
+	if err == nil {
+		return
+	}
+
+	status := CheckPassed
+	if failed == "" {
+		status = CheckSkipped
+	}
+	for i := range r.Checks {
+		if r.Checks[i].Check == failed && status == CheckPassed {
+			r.Checks[i].Status = CheckFailed
+			r.Checks[i].Err = err
+			status = CheckSkipped
+			continue
+		}
+		r.Checks[i].Status = status
+	}
+}
+
+// failedCheck maps the error of a decode to the check which returned it, or "" when the token was not the cause.
+func (t *authManager) failedCheck(token string, signed bool, err error) ValidationCheck {
+	switch {
+	case errors.Is(err, ErrTokenTooLarge):
+		return CheckFormat
+	case errors.Is(err, ErrInvalidTokenType), errors.Is(err, ErrUnsupportedTokenType):
+		return CheckTokenType
+	case errors.Is(err, ErrTokenRevoked):
+		return CheckRevoked
+	case errors.Is(err, ErrTokenTooOld):
+		return CheckAge
+	case errors.Is(err, ErrEpochMismatch), errors.Is(err, ErrSessionExpired), errors.Is(err, ErrSessionSuperseded):
+		return CheckCurrent
+	case errors.Is(err, ErrNoExpiration):
+		return CheckExpiry
+	case errors.Is(err, ErrTokenExpired):
+		// A tracked stateless token which is gone from Redis is reported as expired too.
+		if signed && t.failedJWTCheck(token) == CheckExpiry {
+			return CheckExpiry
+		}
+		return CheckFound
+	case errors.Is(err, ErrInvalidToken):
+		if !signed {
+			return CheckFormat
+		}
+		if check := t.failedJWTCheck(token); check != "" {
+			return check
+		}
+		// The jwt is valid on its own, only an exp beyond MaxExpiryHorizon is rejected with ErrInvalidToken then.
+		return CheckExpiry
+	}
+
+	return ""
+}
+
+// failedJWTCheck parses the token again to tell apart the failures the jwt parser reports, which the decode
+// methods map to ErrInvalidToken.
+func (t *authManager) failedJWTCheck(token string) ValidationCheck {
+	if !validJWTFormat(token) {
+		return CheckFormat
+	}
+
+	_, err := t.parseWithClaims(token, &statelessTokenClaims{}, t.keyFunc)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return CheckFormat
+	case errors.Is(err, jwt.ErrTokenExpired), errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return CheckExpiry
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return CheckNotBefore
+	}
+
+	return CheckSignature
+}
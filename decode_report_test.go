@@ -0,0 +1,123 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenDetailed() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	signAccessToken := func(key string, registeredClaims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(auth_manager.TokenEncodingAlgorithm, signedAccessTokenClaims{
+			Payload: auth_manager.TokenPayload{
+				UUID:      userUUID,
+				TokenType: auth_manager.AccessToken,
+				CreatedAt: time.Now(),
+			},
+			RegisteredClaims: registeredClaims,
+		}).SignedString([]byte(key))
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, report, err := s.authManager.DecodeTokenDetailed(ctx, accessToken, auth_manager.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+	for _, result := range report.Checks {
+		require.Equal(s.T(), auth_manager.CheckPassed, result.Status, result.Check)
+	}
+	_, failed := report.Failed()
+	require.False(s.T(), failed)
+
+	revokedToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	revokedClaims, err := s.authManager.DecodeAccessToken(ctx, revokedToken)
+	require.NoError(s.T(), err)
+	err = s.authManager.RevokeByJTI(ctx, revokedClaims.ID)
+	require.NoError(s.T(), err)
+
+	verifyEmailToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	destroyedToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	err = s.authManager.DestroyPlainToken(ctx, destroyedToken)
+	require.NoError(s.T(), err)
+
+	for name, tc := range map[string]struct {
+		token     string
+		tokenType auth_manager.TokenType
+		check     auth_manager.ValidationCheck
+		err       error
+	}{
+		"malformed access token": {"not-a-token", auth_manager.AccessToken, auth_manager.CheckFormat, auth_manager.ErrInvalidToken},
+		"foreign signature": {signAccessToken("another-key", jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}), auth_manager.AccessToken, auth_manager.CheckSignature, auth_manager.ErrInvalidToken},
+		"expired access token": {signAccessToken("private-key", jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		}), auth_manager.AccessToken, auth_manager.CheckExpiry, auth_manager.ErrTokenExpired},
+		"access token not valid yet": {signAccessToken("private-key", jwt.RegisteredClaims{
+			NotBefore: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
+		}), auth_manager.AccessToken, auth_manager.CheckNotBefore, auth_manager.ErrInvalidToken},
+		"revoked access token":        {revokedToken, auth_manager.AccessToken, auth_manager.CheckRevoked, auth_manager.ErrTokenRevoked},
+		"plain token of another type": {verifyEmailToken, auth_manager.ResetPassword, auth_manager.CheckTokenType, auth_manager.ErrInvalidTokenType},
+		"destroyed plain token":       {destroyedToken, auth_manager.VerifyEmail, auth_manager.CheckFound, auth_manager.ErrTokenExpired},
+		"malformed plain token":       {"short", auth_manager.VerifyEmail, auth_manager.CheckFormat, auth_manager.ErrInvalidToken},
+	} {
+		claims, report, err := s.authManager.DecodeTokenDetailed(ctx, tc.token, tc.tokenType)
+		require.ErrorIs(s.T(), err, tc.err, name)
+		require.Nil(s.T(), claims, name)
+
+		result, failed := report.Failed()
+		require.True(s.T(), failed, name)
+		require.Equal(s.T(), tc.check, result.Check, name)
+		require.ErrorIs(s.T(), result.Err, tc.err, name)
+
+		// The checks before the failed one passed and the ones after it were not run
+		status := auth_manager.CheckPassed
+		for _, result := range report.Checks {
+			if result.Check == tc.check {
+				status = auth_manager.CheckSkipped
+				continue
+			}
+			require.Equal(s.T(), status, result.Status, name, result.Check)
+		}
+	}
+
+	// The ClaimsValidator runs last
+	validatorErr := errors.New("user is banned")
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		ClaimsValidator: func(ctx context.Context, claims *auth_manager.TokenPayload) error {
+			return validatorErr
+		},
+	})
+
+	_, report, err = manager.DecodeTokenDetailed(ctx, accessToken, auth_manager.AccessToken)
+	require.ErrorIs(s.T(), err, validatorErr)
+
+	result, failed := report.Failed()
+	require.True(s.T(), failed)
+	require.Equal(s.T(), auth_manager.CheckClaims, result.Check)
+	require.Equal(s.T(), auth_manager.CheckClaims, report.Checks[len(report.Checks)-1].Check)
+}
@@ -0,0 +1,138 @@
+package auth_manager
+
+import (
+	"context"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DecodeTokenAllowing decodes an access token or a plain token and checks that its type is one of the allowed types,
+// for endpoints which accept more than one kind of token. Each token goes through the same validation as its own
+// decode method; when its type is not allowed ErrInvalidTokenType is returned.
+func (t *authManager) DecodeTokenAllowing(ctx context.Context, token string, allowed ...TokenType) (*TokenPayload, error) {
+	claims, err := t.decodeTokenAllowing(ctx, token, allowed)
+	return claims, t.disclose(err)
+}
+
+func (t *authManager) decodeTokenAllowing(ctx context.Context, token string, allowed []TokenType) (*TokenPayload, error) {
+	token = trimToken(token)
+	if err := t.checkTokenSize(token); err != nil {
+		return nil, err
+	}
+	if isJWT(token) {
+		if !validJWTFormat(token) {
+			return nil, ErrInvalidToken
+		}
+
+		claims := &statelessTokenClaims{}
+		_, err := t.parseWithClaims(token, claims, t.keyFunc)
+		if err != nil {
+			return nil, parseError(err)
+		}
+
+		tokenType := claims.Payload.TokenType
+		if !slices.Contains(allowed, tokenType) {
+			return nil, ErrInvalidTokenType
+		}
+
+		if tokenType == AccessToken {
+			accessClaims, err := t.DecodeAccessToken(ctx, token)
+			if err != nil {
+				return nil, err
+			}
+
+			return &accessClaims.Payload, nil
+		}
+
+		return t.DecodePlainToken(ctx, token, tokenType)
+	}
+
+	if !validOpaqueFormat(token, plainTokenLength) {
+		return nil, ErrInvalidToken
+	}
+
+	claims, err := t.decodeStoredToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(allowed, claims.TokenType) {
+		return nil, ErrInvalidTokenType
+	}
+
+	err = t.validateClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	t.observeTokenAge(claims)
+
+	return claims, nil
+}
+
+// DecodeTokenForPurpose decodes a token of the type like DecodePlainToken, or DecodeAccessToken for access tokens,
+// and rejects it with ErrPurposeMismatch unless it was issued with exactly this Purpose. Tokens of the same type
+// issued for distinct flows can't be used in place of each other.
+func (t *authManager) DecodeTokenForPurpose(ctx context.Context, token string, tokenType TokenType, purpose string) (*TokenPayload, error) {
+	claims, err := t.decodeTokenOfType(ctx, token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, t.disclose(ErrPurposeMismatch)
+	}
+
+	return claims, nil
+}
+
+// decodeTokenOfType decodes a token of the type with DecodeAccessToken or DecodePlainToken.
+func (t *authManager) decodeTokenOfType(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error) {
+	if tokenType == AccessToken {
+		accessClaims, err := t.DecodeAccessToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		return &accessClaims.Payload, nil
+	}
+
+	return t.DecodePlainToken(ctx, token, tokenType)
+}
+
+// DecodeTokenRaw validates a jwt of the type like DecodeAccessToken or DecodePlainToken and returns all of
+// its claims as a map, including claims this package doesn't know about. The payload is nested under "Payload".
+// Plain tokens stored in Redis are not jwt and return ErrUnsupportedTokenType.
+func (t *authManager) DecodeTokenRaw(ctx context.Context, token string, tokenType TokenType) (jwt.MapClaims, error) {
+	claims, err := t.decodeTokenRaw(ctx, token, tokenType)
+	return claims, t.disclose(err)
+}
+
+func (t *authManager) decodeTokenRaw(ctx context.Context, token string, tokenType TokenType) (jwt.MapClaims, error) {
+	token = trimToken(token)
+
+	var err error
+	switch {
+	case tokenType == AccessToken:
+		_, err = t.DecodeAccessToken(ctx, token)
+	case tokenType.plain() && t.stateless(tokenType):
+		_, err = t.DecodePlainToken(ctx, token, tokenType)
+	case !tokenType.valid():
+		err = ErrInvalidTokenType
+	default:
+		err = ErrUnsupportedTokenType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The token has just been verified.
+	claims := jwt.MapClaims{}
+	_, _, err = t.parser.ParseUnverified(token, claims)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,128 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenAllowing() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	resetToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	allowed := []auth_manager.TokenType{auth_manager.AccessToken, auth_manager.ResetPassword}
+
+	decoded, err := s.authManager.DecodeTokenAllowing(ctx, accessToken, allowed...)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), auth_manager.AccessToken, decoded.TokenType)
+	require.Equal(s.T(), userUUID, decoded.UUID)
+
+	decoded, err = s.authManager.DecodeTokenAllowing(ctx, resetToken, allowed...)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), auth_manager.ResetPassword, decoded.TokenType)
+
+	// Out of the allowed set
+	_, err = s.authManager.DecodeTokenAllowing(ctx, accessToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	_, err = s.authManager.DecodeTokenAllowing(ctx, resetToken, auth_manager.AccessToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenForPurpose() {
+	ctx := context.TODO()
+	statelessManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+	})
+
+	for _, tokenType := range []auth_manager.TokenType{auth_manager.ResetPassword, auth_manager.VerifyEmail} {
+		token, err := statelessManager.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+			UUID:      uuid.NewString(),
+			CreatedAt: time.Now(),
+			Purpose:   "password_reset:account_merge",
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		claims, err := statelessManager.DecodeTokenForPurpose(ctx, token, tokenType, "password_reset:account_merge")
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), "password_reset:account_merge", claims.Purpose)
+
+		// Same type, another flow
+		_, err = statelessManager.DecodeTokenForPurpose(ctx, token, tokenType, "password_reset:forgotten")
+		require.ErrorIs(s.T(), err, auth_manager.ErrPurposeMismatch)
+
+		_, err = statelessManager.DecodeTokenForPurpose(ctx, token, tokenType, "")
+		require.ErrorIs(s.T(), err, auth_manager.ErrPurposeMismatch)
+	}
+
+	// Tokens issued without a purpose only match the empty purpose
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenForPurpose(ctx, token, auth_manager.ResetPassword, "")
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenForPurpose(ctx, token, auth_manager.ResetPassword, "password_reset:account_merge")
+	require.ErrorIs(s.T(), err, auth_manager.ErrPurposeMismatch)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenRaw() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := s.authManager.DecodeTokenRaw(ctx, accessToken, auth_manager.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims["sub"])
+	require.Contains(s.T(), claims, "exp")
+	require.Contains(s.T(), claims, "jti")
+
+	payload, ok := claims["Payload"].(map[string]interface{})
+	require.True(s.T(), ok)
+	require.Equal(s.T(), userUUID, payload["uuid"])
+	require.Equal(s.T(), float64(auth_manager.AccessToken), payload["tokenType"])
+
+	// Custom claims of a jwt signed elsewhere with the same key are kept
+	custom, err := jwt.NewWithClaims(auth_manager.TokenEncodingAlgorithm, jwt.MapClaims{
+		"Payload": map[string]interface{}{
+			"uuid":      userUUID,
+			"tokenType": auth_manager.AccessToken,
+			"createdAt": time.Now(),
+		},
+		"exp":  time.Now().Add(time.Minute).Unix(),
+		"role": "admin",
+	}).SignedString([]byte("private-key"))
+	require.NoError(s.T(), err)
+
+	claims, err = s.authManager.DecodeTokenRaw(ctx, custom, auth_manager.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "admin", claims["role"])
+
+	// Validation is the same as the typed decode methods
+	_, err = s.authManager.DecodeTokenRaw(ctx, accessToken[:len(accessToken)-2], auth_manager.AccessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	_, err = s.authManager.DecodeTokenRaw(ctx, accessToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+}
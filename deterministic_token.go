@@ -0,0 +1,29 @@
+package auth_manager
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+)
+
+// deterministicToken derives the plain token of the claims from their uuid, type and purpose, keyed with the
+// signing secret so it can't be computed without it. The digest is TokenByteLength bytes long, so the token has
+// the format of a random one.
+func (t *authManager) deterministicToken(claims *TokenPayload) (string, error) {
+	t.keysMu.RLock()
+	signingKey := t.signingKey
+	t.keysMu.RUnlock()
+
+	if len(signingKey) == 0 {
+		return "", ErrNoSigningSecret
+	}
+
+	mac := hmac.New(sha256.New, signingKey)
+	for _, part := range []string{"plain_token", claims.UUID, strconv.Itoa(int(claims.TokenType)), claims.Purpose} {
+		// Each part is length prefixed, so distinct claims never produce the same input.
+		mac.Write([]byte(strconv.Itoa(len(part)) + ":" + part))
+	}
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
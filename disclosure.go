@@ -0,0 +1,55 @@
+package auth_manager
+
+import "errors"
+
+// DisclosurePolicy decides how much the errors of the decode methods tell about why a token was rejected.
+type DisclosurePolicy int
+
+const (
+	// DiscloseDetailed returns the specific error, such as ErrTokenExpired or ErrTokenRevoked. It is the default.
+	DiscloseDetailed DisclosurePolicy = iota
+
+	// DiscloseOpaque returns an error which only matches ErrInvalidToken for every rejected token, so a response
+	// built from it doesn't tell a token which doesn't exist from a forged one. FailureReason still returns the
+	// specific error for logs and metrics. Failures to check a token, e.g. Redis being unreachable, are unchanged.
+	DiscloseOpaque
+)
+
+// opaqueError is a token error under DiscloseOpaque. It doesn't unwrap to its reason, so errors.Is and errors.As
+// on it only see ErrInvalidToken.
+type opaqueError struct {
+	reason error
+}
+
+func (e *opaqueError) Error() string {
+	return ErrInvalidToken.Error()
+}
+
+func (e *opaqueError) Is(target error) bool {
+	return target == ErrInvalidToken
+}
+
+// FailureReason returns the specific error an error of a decode method under DiscloseOpaque stands for, and the
+// error itself otherwise.
+func FailureReason(err error) error {
+	var opaqueErr *opaqueError
+	if errors.As(err, &opaqueErr) {
+		return opaqueErr.reason
+	}
+
+	return err
+}
+
+// disclose applies the DisclosurePolicy to an error returned by a decode method.
+func (t *authManager) disclose(err error) error {
+	if t.opts.DisclosurePolicy != DiscloseOpaque || !isTokenError(err) {
+		return err
+	}
+
+	var opaqueErr *opaqueError
+	if errors.As(err, &opaqueErr) {
+		return err
+	}
+
+	return &opaqueError{reason: err}
+}
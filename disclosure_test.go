@@ -0,0 +1,72 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DisclosurePolicy() {
+	ctx := context.TODO()
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:       "private-key",
+		DisclosurePolicy: auth_manager.DiscloseOpaque,
+	}
+	manager := auth_manager.NewAuthManager(redisClient, opts)
+
+	destroyedToken, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	err = manager.DestroyPlainToken(ctx, destroyedToken)
+	require.NoError(s.T(), err)
+
+	foreignToken, err := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "another-key",
+	}).GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	_, notFoundErr := manager.DecodePlainToken(ctx, destroyedToken, auth_manager.VerifyEmail)
+	_, forgedErr := manager.DecodeAccessToken(ctx, foreignToken)
+	_, malformedErr := manager.DecodePlainToken(ctx, "short", auth_manager.VerifyEmail)
+	_, forgedIssuedAtErr := manager.GetIssuedAt(foreignToken)
+	_, opaqueIssuedAtErr := manager.GetIssuedAt(destroyedToken)
+
+	// The returned errors can't be told apart
+	for _, err := range []error{notFoundErr, forgedErr, malformedErr, forgedIssuedAtErr, opaqueIssuedAtErr} {
+		require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+		require.NotErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+		require.Equal(s.T(), auth_manager.ErrInvalidToken.Error(), err.Error())
+	}
+
+	// The reason is still available internally
+	require.ErrorIs(s.T(), auth_manager.FailureReason(notFoundErr), auth_manager.ErrTokenExpired)
+	require.ErrorIs(s.T(), auth_manager.FailureReason(forgedErr), auth_manager.ErrInvalidToken)
+	require.ErrorIs(s.T(), auth_manager.FailureReason(opaqueIssuedAtErr), auth_manager.ErrUnsupportedTokenType)
+
+	results, err := manager.ValidateBatch(ctx, []string{destroyedToken}, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.NotErrorIs(s.T(), results[0].Error, auth_manager.ErrTokenExpired)
+
+	// The default policy tells them apart
+	_, err = s.authManager.DecodePlainToken(ctx, destroyedToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+	require.Equal(s.T(), err, auth_manager.FailureReason(err))
+
+	// An outage is not a token error and is returned as it is
+	unreachableClient := redis.NewClient(&redis.Options{
+		Addr:       "127.0.0.1:1",
+		MaxRetries: -1,
+	})
+	defer unreachableClient.Close()
+
+	_, err = auth_manager.NewAuthManager(unreachableClient, opts).DecodePlainToken(ctx, destroyedToken, auth_manager.VerifyEmail)
+	require.Error(s.T(), err)
+	require.NotErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
@@ -5,10 +5,39 @@ import "errors"
 var (
 	ErrInvalidToken            = errors.New("invalid token")
 	ErrInvalidTokenType        = errors.New("invalid token type")
+	ErrUnsupportedTokenType    = errors.New("unsupported token type for this operation")
 	ErrUnexpectedSigningMethod = errors.New("unexpected token signing method")
+	ErrKeyAlgorithmMismatch    = errors.New("signing key does not match the algorithm: HS algorithms take a plain secret, RS/ES algorithms require a PEM key")
+	ErrEmptyUUID               = errors.New("uuid must not be empty")
+	ErrEmptyServiceID          = errors.New("service id must not be empty")
+	ErrMalformedUUID           = errors.New("uuid is not a valid UUID")
 	ErrNotFound                = errors.New("not found")
+	ErrInvalidExpiry           = errors.New("token lifetime must be positive")
 	ErrNoExpiration            = errors.New("no expiration set for the token")
 	ErrTokenExpired            = errors.New("token expired")
+	ErrTokenRevoked            = errors.New("token has been revoked")
+	ErrTokenTooOld             = errors.New("token was issued too long ago")
+	ErrTokenTooYoung           = errors.New("token was issued too recently")
 	ErrEncodingPayload         = errors.New("failed to encode payload to json")
 	ErrDecodingPayload         = errors.New("failed to decode the payload")
+	ErrTokenNotProvided        = errors.New("no token provided")
+	ErrMalformedAuthorization  = errors.New("malformed authorization header")
+	ErrNoRequestInContext      = errors.New("no http request in context")
+	ErrInvalidAudience         = errors.New("token is not intended for this audience")
+	ErrPurposeMismatch         = errors.New("token was issued for another purpose")
+	ErrChallengeMismatch       = errors.New("code verifier does not match the challenge of the token")
+	ErrUserExpired             = errors.New("access of the user has expired")
+	ErrEpochMismatch           = errors.New("token was issued for an older epoch of the user")
+	ErrSessionSuperseded       = errors.New("token was superseded by a newer session of the user")
+	ErrSessionExpired          = errors.New("session has expired or was destroyed")
+	ErrNoInvalidationChannel   = errors.New("no invalidation channel configured")
+	ErrNoSigningSecret         = errors.New("no signing secret configured, set PrivateKey or MasterKey")
+	ErrNoKeyFile               = errors.New("no PrivateKeyFile configured")
+	ErrTokenTooLarge           = errors.New("token exceeds the maximum size")
+	ErrInvalidWrappedToken     = errors.New("unwrapped base64url value is not a valid token")
+	ErrShortCodeExhausted      = errors.New("no free short code found, consider a longer ShortCodeLength")
+	ErrCorruptedEntry          = errors.New("stored token entry is corrupted or has an unknown schema")
+	ErrInvalidCreatedAt        = errors.New("token creation time must be set and not in the future")
+	ErrSubjectMismatch         = errors.New("token subject does not match the uuid of the payload")
+	ErrUserEpochsDisabled      = errors.New("UserEpochs is not set, access tokens can't be revoked by user")
 )
@@ -0,0 +1,259 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ExportedToken is a plain or refresh token read by ExportTokens, in the form ImportTokens writes it back.
+type ExportedToken struct {
+	Token     string
+	TokenType TokenType
+
+	// UUID is the owner of the token.
+	UUID string
+
+	// Value is the value stored for the token, as encoded by the Codec.
+	Value string
+
+	// TTL is the remaining lifetime of the token's Redis entry, zero when it has no expiration.
+	TTL time.Duration
+
+	// Err is set on the last value sent when the export stopped before the whole keyspace was read.
+	Err error
+}
+
+// ExportTokens streams every plain and refresh token stored in Redis, e.g. to back them up or move them to another
+// Redis with ImportTokens. The keyspace is walked with SCAN one batch at a time, so memory use doesn't grow with it.
+// The channel is closed once every token was sent; when the export fails midway, including when ctx is cancelled,
+// the last value carries the error. The channel has to be read until it is closed, as ImportTokens does.
+// Stateless tokens live in the jwt itself and are not exported.
+func (t *authManager) ExportTokens(ctx context.Context) (<-chan ExportedToken, error) {
+	if err := t.redisClient.Ping(ctx).Err(); err != nil {
+		return nil, err
+	}
+
+	tokens := make(chan ExportedToken, scanBatchSize)
+	go func() {
+		defer close(tokens)
+
+		err := t.exportPlainTokens(ctx, tokens)
+		if err == nil {
+			err = t.exportRefreshTokens(ctx, tokens)
+		}
+		if err != nil {
+			// Sent regardless of ctx, which may be the cause, so the reader never takes a truncated export for
+			// a complete one.
+			tokens <- ExportedToken{Err: err}
+		}
+	}()
+
+	return tokens, nil
+}
+
+func (t *authManager) exportPlainTokens(ctx context.Context, tokens chan<- ExportedToken) error {
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generatePlainTokenKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		plainTokens := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if token, ok := t.keys.plainTokenFromKey(key); ok {
+				plainTokens = append(plainTokens, token)
+			}
+		}
+
+		getCmds := make([]*redis.StringCmd, len(plainTokens))
+		ttlCmds := make([]*redis.DurationCmd, len(plainTokens))
+		_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, token := range plainTokens {
+				getCmds[i] = pipe.Get(ctx, t.keys.generatePlainTokenKey(token))
+				ttlCmds[i] = pipe.PTTL(ctx, t.keys.generatePlainTokenKey(token))
+			}
+			return nil
+		})
+		if err != nil && !isReplyError(err) {
+			return err
+		}
+
+		for i, token := range plainTokens {
+			// Keys which expired since the SCAN or are not plain tokens fail with redis.Nil or WRONGTYPE.
+			value, err := getCmds[i].Result()
+			if err != nil {
+				continue
+			}
+
+			claims, err := t.decodePayload(value)
+			if err != nil {
+				continue
+			}
+
+			// The token expired between the GET and the PTTL.
+			ttl, ok := remainingTTL(ttlCmds[i].Val())
+			if !ok {
+				continue
+			}
+
+			err = sendExportedToken(ctx, tokens, ExportedToken{
+				Token:     token,
+				TokenType: claims.TokenType,
+				UUID:      claims.UUID,
+				Value:     value,
+				TTL:       ttl,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func (t *authManager) exportRefreshTokens(ctx context.Context, tokens chan<- ExportedToken) error {
+	hashKeyPrefix := t.keys.generateHashKey("")
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generateHashKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		getCmds := make([]*redis.StringStringMapCmd, len(keys))
+		ttlCmds := make([]*redis.DurationCmd, len(keys))
+		_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				getCmds[i] = pipe.HGetAll(ctx, key)
+				ttlCmds[i] = pipe.PTTL(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && !isReplyError(err) {
+			return err
+		}
+
+		for i, key := range keys {
+			values, err := getCmds[i].Result()
+			if err != nil {
+				continue
+			}
+
+			ttl, ok := remainingTTL(ttlCmds[i].Val())
+			if !ok {
+				continue
+			}
+
+			for token, value := range values {
+				err = sendExportedToken(ctx, tokens, ExportedToken{
+					Token:     token,
+					TokenType: RefreshToken,
+					UUID:      strings.TrimPrefix(key, hashKeyPrefix),
+					Value:     value,
+					TTL:       ttl,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// sendExportedToken blocks until the token is received, or the context is done.
+func sendExportedToken(ctx context.Context, tokens chan<- ExportedToken, token ExportedToken) error {
+	select {
+	case tokens <- token:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// remainingTTL maps the PTTL of a key without expiration to zero, and reports false for a key which is gone,
+// which must not be exported as a token without expiration.
+func remainingTTL(ttl time.Duration) (time.Duration, bool) {
+	switch ttl {
+	case -2:
+		return 0, false
+	case -1:
+		return 0, true
+	}
+
+	return ttl, true
+}
+
+// isReplyError reports whether the error is a reply of Redis to a single command, such as redis.Nil or
+// WRONGTYPE, rather than a failure of the connection.
+func isReplyError(err error) bool {
+	var replyErr redis.Error
+	return errors.As(err, &replyErr)
+}
+
+// ImportTokens writes the tokens read from the channel, usually fed by ExportTokens of another manager, keeping
+// their remaining TTL and adding them to the indexes of their owners. It returns the number of imported tokens
+// once the channel is closed, or the error carried by the last value of a failed export. When a token can't be
+// written the rest of the channel is drained in the background, so the export feeding it is never left blocked;
+// cancel the context of the export to stop it early.
+func (t *authManager) ImportTokens(ctx context.Context, tokens <-chan ExportedToken) (int, error) {
+	imported := 0
+	for token := range tokens {
+		if token.Err != nil {
+			return imported, token.Err
+		}
+
+		err := t.importToken(ctx, token)
+		if err != nil {
+			go drainExportedTokens(tokens)
+			return imported, err
+		}
+		imported++
+	}
+
+	return imported, nil
+}
+
+// drainExportedTokens reads the tokens until the channel is closed.
+func drainExportedTokens(tokens <-chan ExportedToken) {
+	for range tokens {
+	}
+}
+
+func (t *authManager) importToken(ctx context.Context, token ExportedToken) error {
+	if !token.TokenType.valid() {
+		return ErrInvalidTokenType
+	}
+	if token.TokenType == AccessToken || token.TokenType == ServiceToken {
+		return ErrUnsupportedTokenType
+	}
+
+	_, err := t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if token.TokenType == RefreshToken {
+			pipe.HSet(ctx, t.keys.generateHashKey(token.UUID), token.Token, token.Value)
+			extendTTLScript.Eval(ctx, pipe, []string{t.keys.generateHashKey(token.UUID)}, token.TTL.Milliseconds())
+		} else {
+			pipe.Set(ctx, t.keys.generatePlainTokenKey(token.Token), token.Value, token.TTL)
+			pipe.SAdd(ctx, t.keys.generateIndexKey(token.UUID), token.Token)
+			extendTTLScript.Eval(ctx, pipe, []string{t.keys.generateIndexKey(token.UUID)}, token.TTL.Milliseconds())
+		}
+		pipe.SAdd(ctx, t.keys.activeUsersKey(), token.UUID)
+		return nil
+	})
+
+	return err
+}
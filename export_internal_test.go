@@ -0,0 +1,27 @@
+package auth_manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemainingTTL(t *testing.T) {
+	cases := []struct {
+		pttl time.Duration
+		ttl  time.Duration
+		ok   bool
+	}{
+		{pttl: time.Minute, ttl: time.Minute, ok: true},
+		// The key has no expiration
+		{pttl: -1, ttl: 0, ok: true},
+		// The key is gone
+		{pttl: -2, ttl: 0, ok: false},
+	}
+
+	for _, c := range cases {
+		ttl, ok := remainingTTL(c.pttl)
+		if ttl != c.ttl || ok != c.ok {
+			t.Errorf("remainingTTL(%v) = %v, %v, want %v, %v", c.pttl, ttl, ok, c.ttl, c.ok)
+		}
+	}
+}
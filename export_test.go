@@ -0,0 +1,138 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ExportAndImportTokens() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	plainToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Hour)
+	require.NoError(s.T(), err)
+
+	refreshToken, err := s.authManager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{
+		IPAddress: "ip-address",
+	}, time.Hour)
+	require.NoError(s.T(), err)
+
+	targetOptions := *redisClient.Options()
+	targetOptions.DB = 1
+	targetClient := redis.NewClient(&targetOptions)
+	require.NoError(s.T(), targetClient.FlushDB(ctx).Err())
+
+	target := auth_manager.NewAuthManager(targetClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+
+	exported, err := s.authManager.ExportTokens(ctx)
+	require.NoError(s.T(), err)
+
+	// Count the exported tokens on their way to the target
+	counted := make(chan auth_manager.ExportedToken)
+	exportCount := 0
+	go func() {
+		defer close(counted)
+		for token := range exported {
+			exportCount++
+			counted <- token
+		}
+	}()
+
+	imported, err := target.ImportTokens(ctx, counted)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), exportCount, imported)
+	require.GreaterOrEqual(s.T(), imported, 2)
+
+	// Both tokens decode against the target, with their TTL kept
+	claims, err := target.DecodePlainToken(ctx, plainToken, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	ttl, err := targetClient.PTTL(ctx, plainToken).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, time.Minute*59)
+	require.LessOrEqual(s.T(), ttl, time.Hour)
+
+	refreshPayload, err := target.DecodeRefreshToken(ctx, userUUID, refreshToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "ip-address", refreshPayload.IPAddress)
+
+	activeTokens, err := target.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 1)
+
+	require.NoError(s.T(), targetClient.FlushDB(ctx).Err())
+}
+
+func (s *AuthManagerTestSuite) Test_ExportTokensCancelled() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  uuid.NewString(),
+	})
+
+	// More tokens than the channel buffers, so the export is still running when it is cancelled
+	for i := 0; i < 250; i++ {
+		_, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+	}
+
+	exportCtx, cancel := context.WithCancel(ctx)
+	exported, err := manager.ExportTokens(exportCtx)
+	require.NoError(s.T(), err)
+
+	first := <-exported
+	require.NoError(s.T(), first.Err)
+	cancel()
+
+	target := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  uuid.NewString(),
+	})
+
+	imported, err := target.ImportTokens(ctx, exported)
+	require.ErrorIs(s.T(), err, context.Canceled)
+	require.Less(s.T(), imported, 249)
+}
+
+func (s *AuthManagerTestSuite) Test_ImportTokensFailureDrains() {
+	ctx := context.TODO()
+
+	// Unbuffered, so the sender blocks on every token nobody reads
+	tokens := make(chan auth_manager.ExportedToken)
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		defer close(tokens)
+
+		for i := 0; i < 3; i++ {
+			tokens <- auth_manager.ExportedToken{Token: uuid.NewString(), TokenType: auth_manager.AccessToken}
+		}
+	}()
+
+	imported, err := s.authManager.ImportTokens(ctx, tokens)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	require.Zero(s.T(), imported)
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		s.T().Fatal("the tokens after the failed one were not drained")
+	}
+}
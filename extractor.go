@@ -0,0 +1,71 @@
+package auth_manager
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor pulls the raw token out of an incoming request, so the transport specific
+// parsing lives in one place and middlewares can be configured with any source of tokens.
+type TokenExtractor interface {
+	Extract(ctx context.Context) (string, error)
+}
+
+type requestContextKey struct{}
+
+// ContextWithRequest returns a copy of ctx carrying the http request the http extractors read from.
+func ContextWithRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, r)
+}
+
+func requestFromContext(ctx context.Context) (*http.Request, error) {
+	r, ok := ctx.Value(requestContextKey{}).(*http.Request)
+	if !ok || r == nil {
+		return nil, ErrNoRequestInContext
+	}
+
+	return r, nil
+}
+
+// HeaderExtractor reads a bearer token from the Authorization header of the request in the context.
+type HeaderExtractor struct{}
+
+func (HeaderExtractor) Extract(ctx context.Context) (string, error) {
+	r, err := requestFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if header == "" {
+		return "", ErrTokenNotProvided
+	}
+
+	separator := strings.IndexAny(header, " \t")
+	if separator < 0 || !strings.EqualFold(header[:separator], "Bearer") {
+		return "", ErrMalformedAuthorization
+	}
+
+	token := trimToken(header[separator:])
+	if token == "" {
+		return "", ErrMalformedAuthorization
+	}
+
+	return token, nil
+}
+
+// CookieExtractor reads the token from the named cookie of the request in the context.
+// An empty name reads DefaultTokenCookieName.
+type CookieExtractor struct {
+	Name string
+}
+
+func (e CookieExtractor) Extract(ctx context.Context) (string, error) {
+	r, err := requestFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return ReadTokenCookie(r, e.Name)
+}
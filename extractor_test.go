@@ -0,0 +1,67 @@
+package auth_manager_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_HeaderExtractor() {
+	extractor := auth_manager.HeaderExtractor{}
+
+	cases := []struct {
+		header string
+		token  string
+		err    error
+	}{
+		{header: "Bearer token-value", token: "token-value"},
+		{header: "bearer token-value", token: "token-value"},
+		{header: "Bearer\ttoken-value", token: "token-value"},
+		{header: "  Bearer   token-value \n", token: "token-value"},
+		{header: "", err: auth_manager.ErrTokenNotProvided},
+		{header: "Bearer", err: auth_manager.ErrMalformedAuthorization},
+		{header: "Bearer   ", err: auth_manager.ErrMalformedAuthorization},
+		{header: "Basic dXNlcjpwYXNz", err: auth_manager.ErrMalformedAuthorization},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+
+		token, err := extractor.Extract(auth_manager.ContextWithRequest(context.TODO(), r))
+		if c.err != nil {
+			require.ErrorIs(s.T(), err, c.err, c.header)
+			continue
+		}
+		require.NoError(s.T(), err, c.header)
+		require.Equal(s.T(), c.token, token)
+	}
+
+	_, err := extractor.Extract(context.TODO())
+	require.ErrorIs(s.T(), err, auth_manager.ErrNoRequestInContext)
+}
+
+func (s *AuthManagerTestSuite) Test_CookieExtractor() {
+	extractor := auth_manager.CookieExtractor{Name: "token"}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: "token-value"})
+	token, err := extractor.Extract(auth_manager.ContextWithRequest(context.TODO(), r))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "token-value", token)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err = extractor.Extract(auth_manager.ContextWithRequest(context.TODO(), r))
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenNotProvided)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: ""})
+	_, err = extractor.Extract(auth_manager.ContextWithRequest(context.TODO(), r))
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenNotProvided)
+}
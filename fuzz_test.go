@@ -0,0 +1,55 @@
+package auth_manager_test
+
+import (
+	"context"
+	"testing"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+)
+
+var fuzzSeeds = []string{
+	"",
+	".",
+	"..",
+	"...",
+	"a.b.c",
+	"eyJhbGciOiJIUzUxMiJ9..",
+	"eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9.eyJQYXlsb2FkIjpudWxsfQ.",
+	"eyJhbGciOiJub25lIn0.eyJleHAiOiJub3QtYS1udW1iZXIifQ.",
+	"eyJhbGciOjEyM30.e30.e30",
+	"bm90LWEtand0",
+}
+
+func FuzzDecodeAccessToken(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := authManager.DecodeAccessToken(context.TODO(), token)
+		if err == nil {
+			t.Fatalf("decoded a fuzzed access token: %+v", claims)
+		}
+	})
+}
+
+func FuzzDecodePlainToken(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := authManager.DecodePlainToken(context.TODO(), token, auth_manager.VerifyEmail)
+		if err == nil {
+			t.Fatalf("decoded a fuzzed plain token: %+v", claims)
+		}
+	})
+}
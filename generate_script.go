@@ -0,0 +1,85 @@
+package auth_manager
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// storePlainTokenScript writes a plain token, its index entry and its owner in the active users in a single atomic
+// step, and only while the epoch of the user is still the one stamped into the claims. The index is kept alive at
+// least as long as the token, see extendTTLScript.
+//
+// KEYS: the token, the index of the user, the active users, the epoch of the user.
+// ARGV: the encoded claims, the TTL in milliseconds, the token, the uuid, the stamped epoch or "" without UserEpochs.
+//
+// Redis doesn't roll a script back when a command fails halfway, so every check runs before the first write.
+var storePlainTokenScript = redis.NewScript(`
+for _, key in ipairs({KEYS[2], KEYS[3]}) do
+	local keyType = redis.call('TYPE', key).ok
+	if keyType ~= 'none' and keyType ~= 'set' then
+		return redis.error_reply('WRONGTYPE Operation against a key holding the wrong kind of value')
+	end
+end
+
+if ARGV[5] ~= '' and (redis.call('GET', KEYS[4]) or '0') ~= ARGV[5] then
+	return 0
+end
+
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[3])
+local ttl = redis.call('PTTL', KEYS[2])
+if ttl < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+redis.call('SADD', KEYS[3], ARGV[4])
+return 1
+`)
+
+// extendTTLScript makes a key holding several tokens, an index or a refresh token hash, outlive a token just added
+// to it: its TTL is raised to the token's, and a token without a TTL persists the key. A key without a TTL gets one
+// as well, so the key of a user whose last token expired is dropped by Redis instead of staying around forever.
+//
+// KEYS: the index or the refresh token hash of the user.
+// ARGV: the TTL of the token in milliseconds, 0 without a TTL.
+var extendTTLScript = redis.NewScript(`
+local wanted = tonumber(ARGV[1])
+if wanted <= 0 then
+	redis.call('PERSIST', KEYS[1])
+	return 0
+end
+
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < wanted then
+	redis.call('PEXPIRE', KEYS[1], wanted)
+end
+return 0
+`)
+
+// storePlainToken writes a generated plain token with storePlainTokenScript. A generate racing BumpUserEpoch
+// fails with ErrEpochMismatch instead of storing a token which is already rejected.
+func (t *authManager) storePlainToken(ctx context.Context, claims *TokenPayload, token string, encodedClaims []byte, expiresAt time.Duration) error {
+	epoch := ""
+	if t.opts.UserEpochs {
+		epoch = strconv.FormatInt(claims.Epoch, 10)
+	}
+
+	keys := []string{
+		t.keys.generatePlainTokenKey(token),
+		t.keys.generateIndexKey(claims.UUID),
+		t.keys.activeUsersKey(),
+		t.keys.generateEpochKey(claims.UUID),
+	}
+	stored, err := storePlainTokenScript.Run(ctx, t.redisClient, keys,
+		encodedClaims, max(expiresAt.Milliseconds(), 1), token, claims.UUID, epoch).Int()
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return ErrEpochMismatch
+	}
+
+	return nil
+}
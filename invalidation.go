@@ -0,0 +1,73 @@
+package auth_manager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// invalidationMessage is published on the InvalidationChannel. Tokens are identified by their HashToken digest,
+// the key of the decode cache, so the tokens themselves never go over the channel.
+type invalidationMessage struct {
+	JTIs        []string `json:"jtis,omitempty"`
+	TokenHashes []string `json:"tokenHashes,omitempty"`
+	UUIDs       []string `json:"uuids,omitempty"`
+	SessionIDs  []string `json:"sessionIds,omitempty"`
+	KeysChanged bool     `json:"keysChanged,omitempty"`
+}
+
+// publishInvalidation tells the other instances to evict the tokens from their cache. It is best effort:
+// the tokens are already revoked in Redis and a lost message only leaves them cached for up to CacheTTL.
+func (t *authManager) publishInvalidation(ctx context.Context, message invalidationMessage) {
+	if t.opts.InvalidationChannel == "" {
+		return
+	}
+
+	encodedMessage, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	_ = t.redisClient.Publish(ctx, t.opts.InvalidationChannel, encodedMessage).Err()
+}
+
+// SubscribeInvalidations listens on the InvalidationChannel and evicts the tokens revoked or destroyed by other
+// instances from the local cache, until the returned io.Closer is closed. It returns once the subscription is live.
+func (t *authManager) SubscribeInvalidations(ctx context.Context) (io.Closer, error) {
+	if t.opts.InvalidationChannel == "" {
+		return nil, ErrNoInvalidationChannel
+	}
+
+	pubsub := t.redisClient.Subscribe(ctx, t.opts.InvalidationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for message := range pubsub.Channel() {
+			var invalidation invalidationMessage
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				continue
+			}
+
+			if len(invalidation.JTIs) > 0 {
+				t.evictJTIs(invalidation.JTIs)
+			}
+			for _, tokenHash := range invalidation.TokenHashes {
+				t.cache.evictHash(tokenHash)
+			}
+			for _, uuid := range invalidation.UUIDs {
+				t.evictUser(uuid)
+			}
+			for _, sessionID := range invalidation.SessionIDs {
+				t.evictSession(sessionID)
+			}
+			if invalidation.KeysChanged {
+				t.evictAccessTokens()
+			}
+		}
+	}()
+
+	return pubsub, nil
+}
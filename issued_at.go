@@ -0,0 +1,40 @@
+package auth_manager
+
+import "time"
+
+// GetIssuedAt returns when a jwt, an access, service or stateless plain token, was issued, e.g. to rate limit
+// resending a verification link. The signature and the expiration are verified but Redis is never read, so a
+// revoked or destroyed token still returns its time. The iat claim is used, or else the CreatedAt of the payload.
+// Plain tokens stored in Redis are not jwt and return ErrUnsupportedTokenType.
+func (t *authManager) GetIssuedAt(token string) (time.Time, error) {
+	issuedAt, err := t.getIssuedAt(token)
+	return issuedAt, t.disclose(err)
+}
+
+func (t *authManager) getIssuedAt(token string) (time.Time, error) {
+	token = trimToken(token)
+	if err := t.checkTokenSize(token); err != nil {
+		return time.Time{}, err
+	}
+	if !isJWT(token) {
+		return time.Time{}, ErrUnsupportedTokenType
+	}
+	if !validJWTFormat(token) {
+		return time.Time{}, ErrInvalidToken
+	}
+
+	claims := &statelessTokenClaims{}
+	_, err := t.parseWithClaims(token, claims, t.keyFunc)
+	if err != nil {
+		return time.Time{}, parseError(err)
+	}
+
+	if claims.IssuedAt != nil {
+		return claims.IssuedAt.Time, nil
+	}
+	if !claims.Payload.CreatedAt.IsZero() {
+		return claims.Payload.CreatedAt, nil
+	}
+
+	return time.Time{}, ErrInvalidToken
+}
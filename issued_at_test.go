@@ -0,0 +1,73 @@
+package auth_manager_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_GetIssuedAt() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+	}
+	manager := auth_manager.NewAuthManager(redisClient, opts)
+
+	issuedAt := time.Now()
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	statelessToken, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Redis is not needed
+	unreachableClient := redis.NewClient(&redis.Options{
+		Addr:       "127.0.0.1:1",
+		MaxRetries: -1,
+	})
+	defer unreachableClient.Close()
+	offlineManager := auth_manager.NewAuthManager(unreachableClient, opts)
+
+	for _, token := range []string{accessToken, statelessToken} {
+		got, err := offlineManager.GetIssuedAt(token)
+		require.NoError(s.T(), err)
+		require.WithinDuration(s.T(), issuedAt, got, 2*time.Second)
+	}
+
+	// A tampered token is rejected
+	segments := strings.Split(accessToken, ".")
+	claims := []byte(segments[1])
+	if claims[10] == 'A' {
+		claims[10] = 'B'
+	} else {
+		claims[10] = 'A'
+	}
+	segments[1] = string(claims)
+	_, err = offlineManager.GetIssuedAt(strings.Join(segments, "."))
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	foreignManager := auth_manager.NewAuthManager(unreachableClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "another-key",
+	})
+	_, err = foreignManager.GetIssuedAt(accessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// Plain tokens stored in Redis carry no issuance time
+	plainToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = offlineManager.GetIssuedAt(plainToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+}
@@ -0,0 +1,84 @@
+package auth_manager
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// minJanitorInterval is the shortest JanitorInterval, shorter ones would keep the janitor scanning Redis.
+const minJanitorInterval = time.Second
+
+// janitorJitter is the fraction of the interval by which each wait of the janitor is shortened or lengthened,
+// so instances started together don't scan Redis at the same time.
+const janitorJitter = 0.1
+
+// janitor runs the maintenance of JanitorInterval in the background until the manager is closed.
+type janitor struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// startJanitor runs the maintenance about every interval, but no more often than minJanitorInterval. Runs happen
+// one after the other on a single goroutine, the next wait only starts once a run returned so they never overlap.
+func (t *authManager) startJanitor(interval time.Duration) *janitor {
+	interval = max(interval, minJanitorInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &janitor{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(j.done)
+
+		timer := time.NewTimer(jitterInterval(interval))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				t.runJanitor(ctx)
+				timer.Reset(jitterInterval(interval))
+			}
+		}
+	}()
+
+	return j
+}
+
+func jitterInterval(interval time.Duration) time.Duration {
+	return interval + time.Duration((rand.Float64()*2-1)*janitorJitter*float64(interval))
+}
+
+// runJanitor reconciles the indexes and purges the orphans. It is best effort: a failed run is retried by the
+// next one, which starts over. Only the index keys are walked, the full RebuildUserIndex stays an explicit call.
+func (t *authManager) runJanitor(ctx context.Context) {
+	if err := t.reconcileIndexes(ctx); err != nil {
+		return
+	}
+
+	if t.opts.JanitorOrphanAge > 0 {
+		_, _ = t.PurgeOrphans(ctx, t.opts.JanitorOrphanAge)
+	}
+}
+
+// stop cancels the running maintenance, if any, and waits for the goroutine to exit.
+func (j *janitor) stop() {
+	j.closeOnce.Do(func() {
+		j.cancel()
+		<-j.done
+	})
+}
+
+// Close stops the background maintenance of JanitorInterval and waits for a running one to return.
+// It is safe to call more than once; the Redis client is left open for its owner to close.
+func (t *authManager) Close() error {
+	if t.janitor != nil {
+		t.janitor.stop()
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_Janitor() {
+	ctx := context.TODO()
+	prefix := uuid.NewString()
+	userUUID := uuid.NewString()
+	indexKey := prefix + ":plain_token_index:" + userUUID
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:       "private-key",
+		KeyPrefix:        prefix,
+		UserEpochs:       true,
+		JanitorInterval:  time.Second,
+		JanitorOrphanAge: time.Nanosecond,
+	})
+	defer manager.Close()
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// The token is gone but still indexed, and the epoch of the departed user is left behind
+	departedUser := uuid.NewString()
+	require.NoError(s.T(), manager.BumpUserEpoch(ctx, departedUser))
+	require.NoError(s.T(), redisClient.SAdd(ctx, indexKey, "stale-token").Err())
+
+	require.Eventually(s.T(), func() bool {
+		isMember, err := redisClient.SIsMember(ctx, indexKey, "stale-token").Result()
+		return err == nil && !isMember
+	}, 3*time.Second, 10*time.Millisecond)
+
+	require.Eventually(s.T(), func() bool {
+		exists, err := redisClient.Exists(ctx, prefix+":user_epoch:"+departedUser).Result()
+		return err == nil && exists == 0
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Live tokens are kept
+	isMember, err := redisClient.SIsMember(ctx, indexKey, token).Result()
+	require.NoError(s.T(), err)
+	require.True(s.T(), isMember)
+
+	// Nothing is cleaned up once the manager is closed
+	require.NoError(s.T(), manager.Close())
+	require.NoError(s.T(), manager.Close())
+	require.NoError(s.T(), redisClient.SAdd(ctx, indexKey, "stale-token").Err())
+
+	require.Never(s.T(), func() bool {
+		isMember, err := redisClient.SIsMember(ctx, indexKey, "stale-token").Result()
+		return err == nil && !isMember
+	}, 1500*time.Millisecond, 50*time.Millisecond)
+}
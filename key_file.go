@@ -0,0 +1,72 @@
+package auth_manager
+
+import (
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// readKeyFile reads the secret held by a PrivateKeyFile.
+func readKeyFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	key := strings.TrimSpace(string(content))
+	if key == "" {
+		return "", ErrNoSigningSecret
+	}
+	if strings.HasPrefix(key, "-----BEGIN") {
+		return "", ErrKeyAlgorithmMismatch
+	}
+
+	return key, nil
+}
+
+// validateOpts returns the error of reading PrivateKeyFile, or else validates the options.
+func (t *authManager) validateOpts() error {
+	t.keysMu.RLock()
+	keyFileErr := t.keyFileErr
+	t.keysMu.RUnlock()
+
+	if keyFileErr != nil {
+		return keyFileErr
+	}
+
+	return t.opts.Validate()
+}
+
+// ReloadKey reads PrivateKeyFile again and rotates to the key it holds like RotatePrivateKey, e.g. after the
+// secret was rotated or on SIGHUP. When the file can't be read or holds an invalid key, the current key is kept.
+// Cached access tokens are evicted, so a token under a dropped key, e.g. a compromised one, is rejected right away.
+func (t *authManager) ReloadKey() error {
+	if t.opts.PrivateKeyFile == "" {
+		return ErrNoKeyFile
+	}
+
+	key, err := readKeyFile(t.opts.PrivateKeyFile)
+	if err != nil {
+		return err
+	}
+
+	signingKey := t.opts.secret(key)
+
+	t.keysMu.Lock()
+	// Without a key read in the constructor there is no previous key to keep accepting.
+	if t.keyFileErr != nil {
+		t.verificationKey = signingKey
+	} else {
+		t.verificationKey = jwt.VerificationKeySet{
+			Keys: []jwt.VerificationKey{signingKey, t.signingKey},
+		}
+	}
+	t.signingKey = signingKey
+	t.keyFileErr = nil
+	t.keysMu.Unlock()
+
+	t.evictSignedTokens()
+
+	return nil
+}
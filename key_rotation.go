@@ -0,0 +1,45 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RotatePrivateKey replaces the key tokens are signed with, the master key when MasterKey is used, while the
+// manager is in use. The replaced key is still accepted for verification until the next rotation, taking the
+// place of the configured VerificationKeys. It has no effect on a Signer.
+func (t *authManager) RotatePrivateKey(key string) error {
+	if strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+		return ErrKeyAlgorithmMismatch
+	}
+
+	signingKey := t.opts.secret(key)
+
+	t.keysMu.Lock()
+	t.verificationKey = jwt.VerificationKeySet{
+		Keys: []jwt.VerificationKey{signingKey, t.signingKey},
+	}
+	t.signingKey = signingKey
+	t.keysMu.Unlock()
+
+	t.evictSignedTokens()
+
+	return nil
+}
+
+// evictSignedTokens removes the cached access tokens after the verification keys changed, a token signed with a
+// dropped key must not keep being accepted from the cache. The other instances are told to do the same.
+func (t *authManager) evictSignedTokens() {
+	t.evictAccessTokens()
+	t.publishInvalidation(context.Background(), invalidationMessage{KeysChanged: true})
+}
+
+// evictAccessTokens removes every cached access token.
+func (t *authManager) evictAccessTokens() {
+	t.cache.evictFunc(func(value interface{}) bool {
+		_, ok := value.(AccessTokenClaims)
+		return ok
+	})
+}
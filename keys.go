@@ -0,0 +1,120 @@
+package auth_manager
+
+import "strings"
+
+// defaultKeySeparator separates the segments of the Redis keys when AuthManagerOpts.KeySeparator is not set.
+const defaultKeySeparator = ":"
+
+// keyBuilder produces every Redis key of the package following a single scheme:
+//
+//	[KeyPrefix<sep>]<kind><sep><id>
+//
+// where kind names the subsystem, e.g. "refresh_token" or "revoked_jti". Plain tokens are the exception,
+// they are stored under the token itself, prefixed with KeyPrefix when it is set. With the default options
+// the keys are the same as before the scheme was introduced.
+type keyBuilder struct {
+	prefix    string
+	separator string
+}
+
+func newKeyBuilder(opts AuthManagerOpts) keyBuilder {
+	separator := opts.KeySeparator
+	if separator == "" {
+		separator = defaultKeySeparator
+	}
+
+	prefix := ""
+	if opts.KeyPrefix != "" {
+		prefix = opts.KeyPrefix + separator
+	}
+
+	return keyBuilder{prefix: prefix, separator: separator}
+}
+
+func (k keyBuilder) build(kind string, id string) string {
+	return k.prefix + kind + k.separator + id
+}
+
+// generatePlainTokenKey returns the key the claims of the plain token are stored under.
+func (k keyBuilder) generatePlainTokenKey(token string) string {
+	return k.prefix + token
+}
+
+// plainTokenFromKey returns the plain token stored under the key, if the key can hold one.
+func (k keyBuilder) plainTokenFromKey(key string) (string, bool) {
+	token, ok := strings.CutPrefix(key, k.prefix)
+	return token, ok && validOpaqueFormat(token, plainTokenLength)
+}
+
+// activeUsersKey is the set of uuids which have at least one plain or refresh token.
+func (k keyBuilder) activeUsersKey() string {
+	return k.prefix + "active_users"
+}
+
+// generateIndexKey returns the key of the set which holds every plain token issued for the uuid.
+func (k keyBuilder) generateIndexKey(uuid string) string {
+	return k.build("plain_token_index", uuid)
+}
+
+func (k keyBuilder) generateHashKey(uuid string) string {
+	return k.build("refresh_token", uuid)
+}
+
+// refreshTokenOwnerFromKey returns the uuid whose refresh tokens are stored under the key, if the key is a refresh
+// token hash.
+func (k keyBuilder) refreshTokenOwnerFromKey(key string) (string, bool) {
+	uuid, ok := strings.CutPrefix(key, k.generateHashKey(""))
+	return uuid, ok && uuid != ""
+}
+
+func (k keyBuilder) generateRevocationKey(jti string) string {
+	return k.build("revoked_jti", jti)
+}
+
+// generateAuditKey returns the key of the capped list which holds the audit entries of the uuid, newest first.
+func (k keyBuilder) generateAuditKey(uuid string) string {
+	return k.build("audit_log", uuid)
+}
+
+// generateAuditOwnerKey returns the key which remembers the owner of an access token's jti,
+// so RevokeByJTI can record the revocation under the right uuid.
+func (k keyBuilder) generateAuditOwnerKey(jti string) string {
+	return k.build("audit_jti", jti)
+}
+
+func (k keyBuilder) generateSessionKey(sessionID string) string {
+	return k.build("session", sessionID)
+}
+
+// generateCurrentTokenKey returns the key which holds the jti of the user's current access token in SingleSession mode.
+func (k keyBuilder) generateCurrentTokenKey(uuid string) string {
+	return k.build("current_jti", uuid)
+}
+
+func (k keyBuilder) generateEpochKey(uuid string) string {
+	return k.build("user_epoch", uuid)
+}
+
+// generateValidUntilKey returns the key which holds the end of the user's validity set by SetUserValidUntil, in unix milliseconds.
+func (k keyBuilder) generateValidUntilKey(uuid string) string {
+	return k.build("valid_until", uuid)
+}
+
+// generateStatelessTokenKey returns the key which tracks a stateless token by its jti when TrackStatelessTokens is set.
+func (k keyBuilder) generateStatelessTokenKey(jti string) string {
+	return k.build("stateless_token", jti)
+}
+
+// generateTombstoneKey returns the key which keeps the tombstone of a destroyed plain token when SoftDeleteGracePeriod is set.
+func (k keyBuilder) generateTombstoneKey(token string) string {
+	return k.build("tombstone", token)
+}
+
+func (k keyBuilder) generateHandleKey(handle string) string {
+	return k.build("token_handle", handle)
+}
+
+// generateShortCodeKey returns the key which maps a short code of GenerateTokenWithShortCode to its token.
+func (k keyBuilder) generateShortCodeKey(code string) string {
+	return k.build("short_code", code)
+}
@@ -0,0 +1,77 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_KeyScheme() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:   "private-key",
+		KeyPrefix:    "app",
+		KeySeparator: "|",
+	})
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	err = manager.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+
+	_, err = manager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{
+		IPAddress: "ip-address",
+		UserAgent: "user-agent",
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	for _, key := range []string{
+		"app|" + token,
+		"app|plain_token_index|" + userUUID,
+		"app|revoked_jti|" + claims.ID,
+		"app|refresh_token|" + userUUID,
+		"app|active_users",
+	} {
+		exists, err := redisClient.Exists(ctx, key).Result()
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), int64(1), exists, key)
+	}
+
+	// The prefixed token still decodes and is destroyed under its prefixed key
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	err = manager.DestroyPlainToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	exists, err := redisClient.Exists(ctx, "app|"+token).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(0), exists)
+
+	// The default options keep the keys used so far
+	otherUUID := uuid.NewString()
+	token, err = s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      otherUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	exists, err = redisClient.Exists(ctx, token, "plain_token_index:"+otherUUID).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(2), exists)
+}
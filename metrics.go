@@ -0,0 +1,19 @@
+package auth_manager
+
+import "time"
+
+// Metrics receives measurements of the manager, e.g. to export them to Prometheus. Its methods are called
+// synchronously on the request path and must be safe for concurrent use.
+type Metrics interface {
+	// ObserveTokenAge is called on each successful decode with the age of the token, the time since its CreatedAt.
+	ObserveTokenAge(tokenType TokenType, age time.Duration)
+}
+
+// observeTokenAge reports the age of a decoded token to the Metrics. Tokens without a CreatedAt are skipped.
+func (t *authManager) observeTokenAge(claims *TokenPayload) {
+	if t.opts.Metrics == nil || claims.CreatedAt.IsZero() {
+		return
+	}
+
+	t.opts.Metrics.ObserveTokenAge(claims.TokenType, time.Since(claims.CreatedAt))
+}
@@ -0,0 +1,62 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type observedAge struct {
+	tokenType auth_manager.TokenType
+	age       time.Duration
+}
+
+type fakeMetrics struct {
+	ages []observedAge
+}
+
+func (m *fakeMetrics) ObserveTokenAge(tokenType auth_manager.TokenType, age time.Duration) {
+	m.ages = append(m.ages, observedAge{tokenType, age})
+}
+
+func (s *AuthManagerTestSuite) Test_MetricsTokenAge() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	metrics := &fakeMetrics{}
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		Metrics:    metrics,
+	})
+
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	plainToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodePlainToken(ctx, plainToken, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	// Failed decodes are not observed
+	_, err = manager.DecodePlainToken(ctx, plainToken, auth_manager.VerifyEmail)
+	require.Error(s.T(), err)
+
+	require.Len(s.T(), metrics.ages, 2)
+
+	require.Equal(s.T(), auth_manager.AccessToken, metrics.ages[0].tokenType)
+	require.GreaterOrEqual(s.T(), metrics.ages[0].age, time.Duration(0))
+	require.Less(s.T(), metrics.ages[0].age, time.Minute)
+
+	require.Equal(s.T(), auth_manager.ResetPassword, metrics.ages[1].tokenType)
+	require.InDelta(s.T(), time.Hour.Seconds(), metrics.ages[1].age.Seconds(), time.Minute.Seconds())
+}
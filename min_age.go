@@ -0,0 +1,37 @@
+package auth_manager
+
+import (
+	"context"
+	"time"
+)
+
+// DecodeTokenMinAge decodes a token of the type like DecodeTokenForPurpose does and rejects it with
+// ErrTokenTooYoung when it was issued less than minAge ago, e.g. to keep bots from replaying a challenge token
+// right after it was minted. The age is taken from the iat of access tokens and the IssuedAt of plain tokens, never
+// from the CreatedAt the caller passed; a token without either can't prove its age and is rejected too.
+func (t *authManager) DecodeTokenMinAge(ctx context.Context, token string, tokenType TokenType, minAge time.Duration) (*TokenPayload, error) {
+	var claims *TokenPayload
+	var issued time.Time
+	if tokenType == AccessToken {
+		accessClaims, err := t.DecodeAccessToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		claims, issued = &accessClaims.Payload, issuedAt(accessClaims)
+	} else {
+		var err error
+		claims, err = t.DecodePlainToken(ctx, token, tokenType)
+		if err != nil {
+			return nil, err
+		}
+
+		issued = plainIssuedAt(claims)
+	}
+
+	if issued.IsZero() || time.Since(issued) < minAge {
+		return nil, t.disclose(ErrTokenTooYoung)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,56 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenMinAge() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	freshToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenMinAge(ctx, freshToken, auth_manager.VerifyEmail, 30*time.Second)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooYoung)
+
+	// A CreatedAt in the past doesn't make a token minted just now any older
+	backdatedToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now().Add(-time.Minute),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenMinAge(ctx, backdatedToken, auth_manager.VerifyEmail, 30*time.Second)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooYoung)
+
+	time.Sleep(100 * time.Millisecond)
+
+	claims, err := s.authManager.DecodeTokenMinAge(ctx, freshToken, auth_manager.VerifyEmail, 50*time.Millisecond)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	// The token still goes through the checks of its decode method
+	_, err = s.authManager.DecodeTokenMinAge(ctx, freshToken, auth_manager.ResetPassword, 50*time.Millisecond)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	// Access tokens are aged by their iat
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeTokenMinAge(ctx, accessToken, auth_manager.AccessToken, 30*time.Second)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooYoung)
+
+	claims, err = s.authManager.DecodeTokenMinAge(ctx, accessToken, auth_manager.AccessToken, 0)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+}
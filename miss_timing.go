@@ -0,0 +1,41 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// newMissEntry encodes the stand-in decoded for missing plain tokens with EqualizeMissTiming. It has the size
+// of a typical entry and is encoded with the codec of the manager, like a real one.
+func (t *authManager) newMissEntry() string {
+	if !t.opts.EqualizeMissTiming {
+		return ""
+	}
+
+	encoded, err := t.encodePayload(&TokenPayload{
+		UUID:      strings.Repeat("0", 36),
+		CreatedAt: time.Now(),
+		IssuedAt:  time.Now(),
+		Meta:      &TokenMeta{},
+	})
+	if err != nil {
+		return ""
+	}
+
+	return string(encoded)
+}
+
+// equalizeMiss does the work of decoding a found plain token on behalf of a missing one, see EqualizeMissTiming.
+// The checks of the stand-in include reading its user epoch when UserEpochs is set, the round-trip a found
+// token takes.
+func (t *authManager) equalizeMiss(ctx context.Context) {
+	if t.missEntry == "" {
+		return
+	}
+
+	claims, err := t.decodePayload(t.missEntry)
+	if err == nil {
+		_ = t.checkStoredToken(ctx, claims)
+	}
+}
@@ -0,0 +1,84 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// purgeOrphanScript deletes KEYS[1] when it was not accessed for ARGV[1] seconds and none of the other keys exist.
+// The check and the delete run as one step, so a token issued in between keeps the key.
+var purgeOrphanScript = redis.NewScript(`
+local idle = redis.call('OBJECT', 'IDLETIME', KEYS[1])
+if not idle or idle < tonumber(ARGV[1]) then
+	return 0
+end
+
+for i = 2, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		return 0
+	end
+end
+
+return redis.call('DEL', KEYS[1])
+`)
+
+// PurgeOrphans removes the revocation markers and user epochs which were not accessed for olderThan and returns
+// the number of purged keys. Epochs are only purged for users without plain or refresh tokens. The keyspace is
+// walked with SCAN and each key is checked and deleted atomically, so it is safe to run online.
+// A purged marker or epoch no longer rejects the tokens it covered, so olderThan must cover the lifetime of the
+// longest living access token, like RevocationTTL.
+func (t *authManager) PurgeOrphans(ctx context.Context, olderThan time.Duration) (int, error) {
+	purged, err := t.purgeOrphans(ctx, t.keys.generateRevocationKey("*"), olderThan, func(string) []string {
+		return nil
+	})
+	if err != nil {
+		return purged, err
+	}
+
+	epochKeyPrefix := t.keys.generateEpochKey("")
+	purgedEpochs, err := t.purgeOrphans(ctx, t.keys.generateEpochKey("*"), olderThan, func(key string) []string {
+		uuid := strings.TrimPrefix(key, epochKeyPrefix)
+		return []string{t.keys.generateIndexKey(uuid), t.keys.generateHashKey(uuid)}
+	})
+
+	return purged + purgedEpochs, err
+}
+
+// purgeOrphans runs purgeOrphanScript on every key matching the pattern, with the keys returned by liveKeys
+// keeping it.
+func (t *authManager) purgeOrphans(ctx context.Context, pattern string, olderThan time.Duration, liveKeys func(key string) []string) (int, error) {
+	purged := 0
+	minIdleSeconds := int64(olderThan / time.Second)
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return purged, err
+		}
+
+		cmds := make([]*redis.Cmd, len(keys))
+		_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = purgeOrphanScript.Eval(ctx, pipe, append([]string{key}, liveKeys(key)...), minIdleSeconds)
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		for _, cmd := range cmds {
+			count, _ := cmd.Int()
+			purged += count
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return purged, nil
+		}
+	}
+}
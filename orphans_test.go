@@ -0,0 +1,75 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_PurgeOrphans() {
+	ctx := context.TODO()
+	prefix := uuid.NewString()
+	departedUser := uuid.NewString()
+	plainTokenUser := uuid.NewString()
+	refreshTokenUser := uuid.NewString()
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  prefix,
+		UserEpochs: true,
+	})
+
+	for _, userUUID := range []string{departedUser, plainTokenUser, refreshTokenUser} {
+		require.NoError(s.T(), manager.BumpUserEpoch(ctx, userUUID))
+	}
+
+	_, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      plainTokenUser,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.GenerateRefreshToken(ctx, refreshTokenUser, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	require.NoError(s.T(), manager.RevokeByJTI(ctx, "revoked-jti"))
+
+	// Nothing is that old yet
+	purged, err := manager.PurgeOrphans(ctx, time.Hour)
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), purged)
+
+	// The marker and the epoch of the user without tokens are purged
+	purged, err = manager.PurgeOrphans(ctx, 0)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 2, purged)
+
+	exists, err := redisClient.Exists(ctx,
+		prefix+":revoked_jti:revoked-jti",
+		prefix+":user_epoch:"+departedUser,
+	).Result()
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), exists)
+
+	exists, err = redisClient.Exists(ctx,
+		prefix+":user_epoch:"+plainTokenUser,
+		prefix+":user_epoch:"+refreshTokenUser,
+	).Result()
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 2, exists)
+
+	// Keys of other managers are left untouched
+	require.NoError(s.T(), s.authManager.BumpUserEpoch(ctx, departedUser))
+	defer redisClient.Del(ctx, "user_epoch:"+departedUser)
+
+	_, err = manager.PurgeOrphans(ctx, 0)
+	require.NoError(s.T(), err)
+
+	exists, err = redisClient.Exists(ctx, "user_epoch:"+departedUser).Result()
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 1, exists)
+}
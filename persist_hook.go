@@ -0,0 +1,55 @@
+package auth_manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PersistHook records a generated plain token outside of Redis, see AuthManagerOpts.PersistHook.
+type PersistHook func(ctx context.Context, claims *TokenPayload, token string) error
+
+// persistToken runs the PersistHook and rolls the token back when it fails, so Redis and the hook's store agree.
+// The error of a failed rollback is wrapped together with the error of the hook.
+func (t *authManager) persistToken(ctx context.Context, claims *TokenPayload, token string) error {
+	if t.opts.PersistHook == nil {
+		return nil
+	}
+
+	err := t.opts.PersistHook(ctx, claims, token)
+	if err == nil {
+		return nil
+	}
+
+	if rollbackErr := t.rollbackToken(ctx, claims, token); rollbackErr != nil {
+		return fmt.Errorf("%w: %w", err, rollbackErr)
+	}
+
+	return err
+}
+
+// rollbackToken removes what generating the token wrote to Redis. Nothing else saw the token yet,
+// so unlike DestroyPlainToken it leaves no audit entry, tombstone or invalidation behind.
+func (t *authManager) rollbackToken(ctx context.Context, claims *TokenPayload, token string) error {
+	if t.stateless(claims.TokenType) {
+		if !t.opts.TrackStatelessTokens {
+			return nil
+		}
+
+		_, err := t.destroyStatelessToken(ctx, token)
+		return err
+	}
+
+	_, err := t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, t.keys.generatePlainTokenKey(token))
+		pipe.SRem(ctx, t.keys.generateIndexKey(claims.UUID), token)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.pruneActiveUser(ctx, claims.UUID)
+	return err
+}
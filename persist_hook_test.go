@@ -0,0 +1,61 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_PersistHook() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	records := map[string]auth_manager.TokenPayload{}
+	var hookErr error
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		PersistHook: func(ctx context.Context, claims *auth_manager.TokenPayload, token string) error {
+			if hookErr != nil {
+				return hookErr
+			}
+			records[token] = *claims
+			return nil
+		},
+	})
+
+	// A persisted token is stored in both places
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, records[token].UUID)
+	require.Equal(s.T(), auth_manager.VerifyEmail, records[token].TokenType)
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	// A failing hook rolls the token back
+	hookErr = errors.New("database is down")
+	otherUUID := uuid.NewString()
+	token, err = manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      otherUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.ErrorIs(s.T(), err, hookErr)
+	require.Empty(s.T(), token)
+	require.Len(s.T(), records, 1)
+
+	activeTokens, err := manager.ListActiveTokens(ctx, otherUUID)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), activeTokens)
+
+	isMember, err := redisClient.SIsMember(ctx, "active_users", otherUUID).Result()
+	require.NoError(s.T(), err)
+	require.False(s.T(), isMember)
+}
@@ -2,52 +2,492 @@ package auth_manager
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // Used for ResetPassword, VerifyEmail, SessionBasedAuthentication, etc.
 func (t *authManager) GeneratePlainToken(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, error) {
-	token, err := generateRandomString(TokenByteLength)
+	if !tokenType.valid() {
+		return "", ErrInvalidTokenType
+	}
+	if !tokenType.plain() {
+		return "", ErrUnsupportedTokenType
+	}
+	if err := t.opts.checkAllowedTokenType(tokenType); err != nil {
+		return "", err
+	}
+	if payload == nil {
+		return "", ErrEmptyUUID
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", err
+	}
+
+	uuid, err := t.opts.validateUUID(payload.UUID)
 	if err != nil {
 		return "", err
 	}
 
-	claimsJson, err := json.Marshal(&payload)
+	// The caller's payload is left untouched.
+	normalized := *payload
+	normalized.UUID = uuid
+	normalized.IssuedAt = time.Now()
+	payload = &normalized
+
+	expiresAt, err = t.clampExpiry(ctx, uuid, t.jitterExpiry(expiresAt))
 	if err != nil {
 		return "", err
 	}
 
-	cmd := t.redisClient.Set(ctx, token, claimsJson, expiresAt)
-	if cmd.Err() != nil {
-		return "", cmd.Err()
+	epoch, err := t.userEpoch(ctx, payload.UUID)
+	if err != nil {
+		return "", err
+	}
+
+	claims := *payload
+	claims.TokenType = tokenType
+	claims.Epoch = epoch
+
+	if err := claims.Validate(); err != nil {
+		return "", err
+	}
+
+	if t.stateless(tokenType) {
+		token, err := t.generateStatelessToken(ctx, tokenType, payload, epoch, expiresAt)
+		if err != nil {
+			return "", err
+		}
+
+		err = t.persistToken(ctx, &claims, token)
+		if err != nil {
+			return "", err
+		}
+
+		t.audit(ctx, AuditEntry{Event: AuditIssued, UUID: payload.UUID, TokenType: tokenType})
+
+		return token, nil
 	}
 
+	token, err := t.newPlainToken(&claims)
+	if err != nil {
+		return "", err
+	}
+
+	encodedClaims, err := t.encodePayload(&claims)
+	if err != nil {
+		return "", ErrEncodingPayload
+	}
+
+	// The token and its index entry are written atomically in a single round-trip.
+	err = t.storePlainToken(ctx, &claims, token, encodedClaims, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.persistToken(ctx, &claims, token)
+	if err != nil {
+		return "", err
+	}
+
+	t.audit(ctx, AuditEntry{Event: AuditIssued, UUID: payload.UUID, TokenType: tokenType})
+
 	return token, nil
 }
 
+// newPlainToken returns a random plain token, or the one derived from the claims with DeterministicPlainTokens.
+func (t *authManager) newPlainToken(claims *TokenPayload) (string, error) {
+	if t.opts.DeterministicPlainTokens {
+		return t.deterministicToken(claims)
+	}
+
+	return generateRandomString(TokenByteLength)
+}
+
+// GeneratePlainTokenWithHash works like GeneratePlainToken and also returns the HashToken digest of the token,
+// so the token can be handed to the user while only its hash is logged.
+func (t *authManager) GeneratePlainTokenWithHash(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, string, error) {
+	token, err := t.GeneratePlainToken(ctx, tokenType, payload, expiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, HashToken(token), nil
+}
+
+// DecodePlainToken reads the claims stored for the token. Plain tokens are opaque, so the
+// Redis TTL is the only expiration they have: a token which is not found has expired and
+// ErrTokenExpired is returned.
+//
+// A decode racing DestroyPlainToken returns the claims when it read the token before it was deleted, and
+// ErrTokenExpired, or ErrTokenRevoked with SoftDeleteGracePeriod, otherwise. Once the destroy returned no decode
+// succeeds, the decode cache included.
 func (t *authManager) DecodePlainToken(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error) {
-	claimsString, err := t.redisClient.Get(ctx, token).Result()
+	claims, err := t.decodePlainToken(ctx, trimToken(token), tokenType)
 	if err != nil {
+		return nil, t.disclose(err)
+	}
+
+	err = t.validateClaims(ctx, claims)
+	if err != nil {
+		return nil, t.disclose(err)
+	}
+
+	t.observeTokenAge(claims)
+
+	return claims, nil
+}
+
+func (t *authManager) decodePlainToken(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error) {
+	if err := t.checkTokenSize(token); err != nil {
 		return nil, err
 	}
+	if !tokenType.valid() {
+		return nil, ErrInvalidTokenType
+	}
+	if !tokenType.plain() {
+		return nil, ErrUnsupportedTokenType
+	}
 
-	claims := &TokenPayload{}
+	if t.stateless(tokenType) {
+		return t.decodeStatelessToken(ctx, token, tokenType)
+	}
 
-	err = json.Unmarshal([]byte(claimsString), &claims)
-	if err != nil {
+	if isJWT(token) {
+		return nil, ErrUnsupportedTokenType
+	}
+	if !validOpaqueFormat(token, plainTokenLength) {
 		return nil, ErrInvalidToken
 	}
 
+	claims, err := t.decodeStoredToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, ErrInvalidTokenType
+	}
+
 	return claims, nil
 }
 
+// decodeStoredToken reads the claims stored in Redis for a plain token, whatever its type is.
+func (t *authManager) decodeStoredToken(ctx context.Context, token string) (*TokenPayload, error) {
+	if cached, ok := t.cache.get(token); ok {
+		if cachedClaims, ok := cached.(TokenPayload); ok {
+			if t.plainTokenTooOld(&cachedClaims) {
+				return nil, ErrTokenTooOld
+			}
+
+			return &cachedClaims, nil
+		}
+	}
+	cacheVersion := t.cache.version()
+
+	claimsString, ttl, tombstoneCmd, err := t.getPlainToken(ctx, token)
+	if errors.Is(err, redis.Nil) {
+		t.equalizeMiss(ctx)
+		if tombstoneCmd != nil {
+			return nil, t.tombstoneError(tombstoneCmd.Result())
+		}
+		return nil, t.missingTokenError(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := t.decodePayload(claimsString)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.checkStoredToken(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		t.cache.set(token, claims.UUID, *claims, time.Now().Add(ttl), cacheVersion)
+	}
+
+	return claims, nil
+}
+
+// checkStoredToken runs the checks of the claims read from Redis for a plain token.
+func (t *authManager) checkStoredToken(ctx context.Context, claims *TokenPayload) error {
+	if t.plainTokenTooOld(claims) {
+		return ErrTokenTooOld
+	}
+
+	return t.checkUserEpoch(ctx, claims)
+}
+
+// plainTokenTooOld reports whether the stored CreatedAt or IssuedAt of the plain token is older than PlainTokenMaxAge
+// allows for its type.
+func (t *authManager) plainTokenTooOld(claims *TokenPayload) bool {
+	maxAge, ok := t.opts.PlainTokenMaxAge[claims.TokenType]
+	if !ok || maxAge <= 0 {
+		return false
+	}
+
+	oldest := plainIssuedAt(claims)
+	if claims.CreatedAt.Before(oldest) {
+		oldest = claims.CreatedAt
+	}
+
+	return oldest.Add(maxAge).Before(time.Now())
+}
+
+// plainIssuedAt returns the IssuedAt of the plain token, falling back to its CreatedAt for tokens generated before
+// IssuedAt was recorded.
+func plainIssuedAt(claims *TokenPayload) time.Time {
+	if !claims.IssuedAt.IsZero() {
+		return claims.IssuedAt
+	}
+
+	return claims.CreatedAt
+}
+
+// getPlainToken reads the stored claims of the token. The remaining TTL is only fetched,
+// in the same round-trip, when the decode cache needs it. With EqualizeMissTiming and SoftDeleteGracePeriod
+// the tombstone is fetched in that round-trip too, so a miss doesn't take one more than a found token.
+func (t *authManager) getPlainToken(ctx context.Context, token string) (string, time.Duration, *redis.StringCmd, error) {
+	prefetchTombstone := t.missEntry != "" && t.opts.SoftDeleteGracePeriod > 0
+	if t.cache == nil && !prefetchTombstone {
+		claimsString, err := t.redisClient.Get(ctx, t.keys.generatePlainTokenKey(token)).Result()
+		return claimsString, 0, nil, err
+	}
+
+	var getCmd, tombstoneCmd *redis.StringCmd
+	var ttlCmd *redis.DurationCmd
+	_, err := t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, t.keys.generatePlainTokenKey(token))
+		if t.cache != nil {
+			ttlCmd = pipe.PTTL(ctx, t.keys.generatePlainTokenKey(token))
+		}
+		if prefetchTombstone {
+			tombstoneCmd = pipe.Get(ctx, t.keys.generateTombstoneKey(token))
+		}
+		return nil
+	})
+	if err != nil && !isReplyError(err) {
+		return "", 0, nil, err
+	}
+	if err := getCmd.Err(); err != nil {
+		return "", 0, tombstoneCmd, err
+	}
+
+	var ttl time.Duration
+	if ttlCmd != nil {
+		ttl = ttlCmd.Val()
+	}
+
+	return getCmd.Val(), ttl, tombstoneCmd, nil
+}
+
 // The Destroy method is simply used to remove a key from Redis Store.
+// The stored claims are read before deletion so the token can also be removed from
+// its owner's index. A token that has already expired is simply deleted.
 func (t *authManager) DestroyPlainToken(ctx context.Context, key string) error {
-	cmd := t.redisClient.Del(ctx, key)
-	if cmd.Err() != nil {
-		return cmd.Err()
+	_, err := t.DestroyPlainTokenExists(ctx, key)
+	return err
+}
+
+// DestroyPlainTokenExists works like DestroyPlainToken and also reports whether the token still existed,
+// so callers can tell a logout which invalidated a token from a double logout or an already expired token.
+func (t *authManager) DestroyPlainTokenExists(ctx context.Context, key string) (bool, error) {
+	return t.DestroyPlainTokenWithReason(ctx, key, "")
+}
+
+// DestroyPlainTokenWithReason works like DestroyPlainTokenExists and records the reason in the tombstone of the token
+// when SoftDeleteGracePeriod is set, e.g. "password changed", so a later decode tells why the token stopped working.
+func (t *authManager) DestroyPlainTokenWithReason(ctx context.Context, key string, reason string) (bool, error) {
+	if isJWT(key) && t.opts.TrackStatelessTokens {
+		return t.destroyStatelessToken(ctx, key)
+	}
+
+	claimsString, err := t.redisClient.Get(ctx, t.keys.generatePlainTokenKey(key)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+
+	owner := ""
+	var tokenType TokenType
+	if err == nil {
+		if claims, err := t.decodePayload(claimsString); err == nil {
+			owner = claims.UUID
+			tokenType = claims.TokenType
+			err = t.redisClient.SRem(ctx, t.keys.generateIndexKey(owner), key).Err()
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	deleted, err := t.redisClient.Del(ctx, t.keys.generatePlainTokenKey(key)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if deleted > 0 {
+		t.writeTombstone(ctx, key, reason)
 	}
 
+	t.cache.evict(key)
+	t.publishInvalidation(ctx, invalidationMessage{TokenHashes: []string{HashToken(key)}})
+
+	if owner != "" {
+		if deleted > 0 {
+			t.audit(ctx, AuditEntry{Event: AuditRevoked, UUID: owner, TokenType: tokenType})
+		}
+
+		_, err = t.pruneActiveUser(ctx, owner)
+		if err != nil {
+			return deleted > 0, err
+		}
+	}
+
+	return deleted > 0, nil
+}
+
+// touchTokenScript writes the claims of a touched plain token, only while the token still holds the claims they
+// were derived from, keeping its TTL.
+//
+// KEYS: the token.
+// ARGV: the claims read, the touched claims.
+var touchTokenScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+return 1
+`)
+
+// touchAttempts is how many times TouchToken reads the claims again when they changed before it could write them.
+const touchAttempts = 3
+
+// TouchToken records the current time as the LastUsed of the plain token's metadata.
+// The TTL of the token is left untouched and a token which expired in the meantime is not written back. The claims
+// are only replaced when nobody wrote the token since they were read, e.g. ReissueToken, so a concurrent write is
+// never overwritten with stale claims; when the token keeps changing the touch is given up after a few attempts.
+func (t *authManager) TouchToken(ctx context.Context, token string) error {
+	token = trimToken(token)
+	if err := t.checkTokenSize(token); err != nil {
+		return err
+	}
+	if isJWT(token) {
+		return ErrUnsupportedTokenType
+	}
+	if !validOpaqueFormat(token, plainTokenLength) {
+		return ErrInvalidToken
+	}
+
+	key := t.keys.generatePlainTokenKey(token)
+	for attempt := 0; attempt < touchAttempts; attempt++ {
+		claimsString, err := t.redisClient.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrTokenExpired
+		}
+		if err != nil {
+			return err
+		}
+
+		claims, err := t.decodePayload(claimsString)
+		if err != nil {
+			return err
+		}
+
+		if claims.Meta == nil {
+			claims.Meta = &TokenMeta{}
+		}
+		claims.Meta.LastUsed = time.Now()
+
+		encodedClaims, err := t.encodePayload(claims)
+		if err != nil {
+			return ErrEncodingPayload
+		}
+
+		touched, err := touchTokenScript.Run(ctx, t.redisClient, []string{key}, claimsString, encodedClaims).Int()
+		if err != nil {
+			return err
+		}
+		if touched == 1 {
+			break
+		}
+	}
+
+	t.cache.evict(token)
+
 	return nil
 }
+
+// ActiveToken is a live plain token of a user as listed by ListActiveTokens.
+type ActiveToken struct {
+	Token     string
+	Payload   *TokenPayload
+	ExpiresIn time.Duration
+}
+
+// ListActiveTokens returns the live plain tokens of the user along with their stored claims and metadata.
+// Tokens which already expired are dropped from the user's index on the way.
+func (t *authManager) ListActiveTokens(ctx context.Context, uuid string) ([]ActiveToken, error) {
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := t.redisClient.SMembers(ctx, t.keys.generateIndexKey(uuid)).Result()
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	getCmds := make([]*redis.StringCmd, len(tokens))
+	ttlCmds := make([]*redis.DurationCmd, len(tokens))
+	_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, token := range tokens {
+			getCmds[i] = pipe.Get(ctx, t.keys.generatePlainTokenKey(token))
+			ttlCmds[i] = pipe.PTTL(ctx, t.keys.generatePlainTokenKey(token))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	activeTokens := make([]ActiveToken, 0, len(tokens))
+	expired := []interface{}{}
+	for i, token := range tokens {
+		claimsString, err := getCmds[i].Result()
+		if errors.Is(err, redis.Nil) {
+			expired = append(expired, token)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		claims, err := t.decodePayload(claimsString)
+		if err != nil {
+			return nil, err
+		}
+
+		activeTokens = append(activeTokens, ActiveToken{
+			Token:     token,
+			Payload:   claims,
+			ExpiresIn: ttlCmds[i].Val(),
+		})
+	}
+
+	if len(expired) > 0 {
+		err = t.redisClient.SRem(ctx, t.keys.generateIndexKey(uuid), expired...).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return activeTokens, nil
+}
@@ -0,0 +1,1038 @@
+package auth_manager_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DestroyPlainTokenRemovesIndexEntry() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	indexKey := "plain_token_index:" + userUUID
+	payload := &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+
+	isMember, err := redisClient.SIsMember(ctx, indexKey, token).Result()
+	require.NoError(s.T(), err)
+	require.True(s.T(), isMember)
+
+	err = s.authManager.DestroyPlainToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	isMember, err = redisClient.SIsMember(ctx, indexKey, token).Result()
+	require.NoError(s.T(), err)
+	require.False(s.T(), isMember)
+
+	// Destroying a token that is already gone must not fail
+	err = s.authManager.DestroyPlainToken(ctx, token)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_PlainTokenIndexTTL() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	indexKey := "plain_token_index:" + userUUID
+	payload := &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}
+
+	// An index left without a TTL gets one with the next token
+	require.NoError(s.T(), redisClient.SAdd(ctx, indexKey, "legacy-token").Err())
+
+	_, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Hour)
+	require.NoError(s.T(), err)
+
+	ttl, err := redisClient.PTTL(ctx, indexKey).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, 59*time.Minute)
+
+	// A shorter token doesn't shorten the index
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+
+	ttl, err = redisClient.PTTL(ctx, indexKey).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, 59*time.Minute)
+
+	// A longer one extends it
+	_, err = s.authManager.ReissueToken(ctx, token, 2*time.Hour)
+	require.NoError(s.T(), err)
+
+	ttl, err = redisClient.PTTL(ctx, indexKey).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, 119*time.Minute)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodePlainTokenAfterRedisTTL() {
+	ctx := context.TODO()
+	payload := &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Millisecond*200)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	time.Sleep(time.Millisecond * 400)
+
+	decoded, err := s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+	require.Nil(s.T(), decoded)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeWrongTokenType() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	plainToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Access token passed to the plain and refresh token decoders
+	_, err = s.authManager.DecodePlainToken(ctx, accessToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+
+	_, err = s.authManager.DecodeRefreshToken(ctx, userUUID, accessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+
+	// Plain token decoded as a stateless token type
+	_, err = s.authManager.DecodePlainToken(ctx, plainToken, auth_manager.AccessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+
+	_, err = s.authManager.DecodeAccessToken(ctx, plainToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// Plain token decoded as another plain token type
+	_, err = s.authManager.DecodePlainToken(ctx, plainToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+}
+
+type failingPipelineHook struct{}
+
+func (failingPipelineHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (failingPipelineHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (failingPipelineHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, errors.New("simulated pipeline failure")
+}
+
+func (failingPipelineHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_GeneratePlainTokenIsAtomic() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	prefix := uuid.NewString()
+	indexKey := prefix + ":plain_token_index:" + userUUID
+
+	// Deterministic tokens tell which key the failed generate would have written
+	newManager := func(keyPrefix string) auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:               "private-key",
+			KeyPrefix:                keyPrefix,
+			DeterministicPlainTokens: true,
+		})
+	}
+	generate := func(manager auth_manager.AuthManager) (string, error) {
+		return manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			TokenType: auth_manager.VerifyEmail,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+	}
+
+	// The index of the user can't take the token, which Redis only finds out after writing the token itself
+	err := redisClient.Set(ctx, indexKey, "not-a-set", time.Minute).Err()
+	require.NoError(s.T(), err)
+	defer redisClient.Del(ctx, indexKey)
+
+	token, err := generate(newManager(prefix))
+	require.Error(s.T(), err)
+	require.Empty(s.T(), token)
+
+	token, err = generate(newManager(uuid.NewString()))
+	require.NoError(s.T(), err)
+
+	// Neither the token, its index entry nor the active user was written
+	exists, err := redisClient.Exists(ctx, prefix+":"+token).Result()
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), exists)
+
+	index, err := redisClient.Get(ctx, indexKey).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "not-a-set", index)
+
+	isMember, err := redisClient.SIsMember(ctx, prefix+":active_users", userUUID).Result()
+	require.NoError(s.T(), err)
+	require.False(s.T(), isMember)
+}
+
+func (s *AuthManagerTestSuite) Test_GeneratePlainTokenRacingEpochBump() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		UserEpochs: true,
+	})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var tokens []string
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+				UUID:      userUUID,
+				TokenType: auth_manager.VerifyEmail,
+				CreatedAt: time.Now(),
+			}, time.Minute)
+			if errors.Is(err, auth_manager.ErrEpochMismatch) {
+				return
+			}
+			require.NoError(s.T(), err)
+
+			mu.Lock()
+			tokens = append(tokens, token)
+			mu.Unlock()
+		}()
+		go func() {
+			defer wg.Done()
+			err := authManager.BumpUserEpoch(ctx, userUUID)
+			require.NoError(s.T(), err)
+		}()
+	}
+	wg.Wait()
+
+	// Every token which was returned is both stored and indexed
+	indexed, err := redisClient.SMembers(ctx, "plain_token_index:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.ElementsMatch(s.T(), tokens, indexed)
+
+	epoch, err := redisClient.Get(ctx, "user_epoch:"+userUUID).Int64()
+	require.NoError(s.T(), err)
+	for _, token := range tokens {
+		value, err := redisClient.Get(ctx, token).Result()
+		require.NoError(s.T(), err)
+		require.NotEmpty(s.T(), value)
+	}
+	require.EqualValues(s.T(), 20, epoch)
+}
+
+func (s *AuthManagerTestSuite) Test_GeneratePlainTokenWithHash() {
+	ctx := context.TODO()
+
+	token, hash, err := s.authManager.GeneratePlainTokenWithHash(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	require.NotEmpty(s.T(), token)
+
+	sum := sha256.Sum256([]byte(token))
+	require.Equal(s.T(), hex.EncodeToString(sum[:]), hash)
+	require.Equal(s.T(), auth_manager.HashToken(token), hash)
+
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeCorruptedEntry() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	err = redisClient.Set(ctx, token, "{not-json", time.Minute).Err()
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrCorruptedEntry)
+
+	refreshToken, err := s.authManager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	err = redisClient.HSet(ctx, "refresh_token:"+userUUID, refreshToken, "null").Err()
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeRefreshToken(ctx, userUUID, refreshToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrCorruptedEntry)
+}
+
+func (s *AuthManagerTestSuite) Test_GeneratePlainTokenInvalidType() {
+	ctx := context.TODO()
+	payload := &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.TokenType(99), payload, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+	require.Empty(s.T(), token)
+
+	token, err = s.authManager.GeneratePlainToken(ctx, auth_manager.AccessToken, payload, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+	require.Empty(s.T(), token)
+
+	_, err = s.authManager.DecodePlainToken(ctx, "token", auth_manager.TokenType(-1))
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+}
+
+func (s *AuthManagerTestSuite) Test_StatelessPlainToken() {
+	ctx := context.TODO()
+
+	// Nothing listens on this address, any Redis call would fail
+	unavailableClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer unavailableClient.Close()
+
+	authManager := auth_manager.NewAuthManager(unavailableClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.ResetPassword},
+	})
+	payload := &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}
+
+	token, err := authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+
+	decoded, err := authManager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), payload.UUID, decoded.UUID)
+	require.Equal(s.T(), auth_manager.ResetPassword, decoded.TokenType)
+
+	// Stateful types still need Redis
+	_, err = authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Minute)
+	require.Error(s.T(), err)
+
+	// Expired stateless tokens are rejected
+	expiredToken, err := jwt.NewWithClaims(auth_manager.TokenEncodingAlgorithm, signedAccessTokenClaims{
+		Payload: auth_manager.TokenPayload{
+			UUID:      payload.UUID,
+			TokenType: auth_manager.ResetPassword,
+			CreatedAt: time.Now().Add(-time.Hour),
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}).SignedString([]byte("private-key"))
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, expiredToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
+
+func (s *AuthManagerTestSuite) Test_ListActiveTokensWithMeta() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	meta := &auth_manager.TokenMeta{
+		IPAddress: "192.168.1.1",
+		UserAgent: "Mozilla/5.0",
+		DeviceID:  "device-id",
+	}
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+		Meta:      meta,
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	decoded, err := s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), meta, decoded.Meta)
+
+	activeTokens, err := s.authManager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 1)
+	require.Equal(s.T(), token, activeTokens[0].Token)
+	require.Equal(s.T(), meta, activeTokens[0].Payload.Meta)
+	require.Positive(s.T(), activeTokens[0].ExpiresIn)
+
+	// Stateless tokens drop the metadata unless asked to sign it
+	for _, signMeta := range []bool{false, true} {
+		authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:          "private-key",
+			StatelessTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+			SignTokenMeta:       signMeta,
+		})
+
+		token, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			TokenType: auth_manager.VerifyEmail,
+			CreatedAt: time.Now(),
+			Meta:      meta,
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		decoded, err := authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err)
+		if signMeta {
+			require.Equal(s.T(), meta, decoded.Meta)
+		} else {
+			require.Nil(s.T(), decoded.Meta)
+		}
+	}
+}
+
+func (s *AuthManagerTestSuite) Test_TouchToken() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Hour)
+	require.NoError(s.T(), err)
+
+	err = redisClient.Expire(ctx, token, time.Minute*30).Err()
+	require.NoError(s.T(), err)
+
+	before := time.Now()
+	err = s.authManager.TouchToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	decoded, err := s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), decoded.Meta)
+	require.False(s.T(), decoded.Meta.LastUsed.Before(before))
+
+	ttl, err := redisClient.TTL(ctx, token).Result()
+	require.NoError(s.T(), err)
+	require.LessOrEqual(s.T(), ttl, time.Minute*30)
+	require.Greater(s.T(), ttl, time.Minute*29)
+
+	// Touching again advances LastUsed
+	firstUse := decoded.Meta.LastUsed
+	time.Sleep(time.Millisecond * 10)
+	err = s.authManager.TouchToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	activeTokens, err := s.authManager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 1)
+	require.True(s.T(), activeTokens[0].Payload.Meta.LastUsed.After(firstUse))
+
+	// Destroyed tokens are not written back
+	err = s.authManager.DestroyPlainToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	err = s.authManager.TouchToken(ctx, token)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
+
+func (s *AuthManagerTestSuite) Test_TouchTokenConcurrentWrite() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:               "private-key",
+		DeterministicPlainTokens: true,
+	}
+
+	hook := &afterGetHook{}
+	hookedClient := redis.NewClient(redisClient.Options())
+	hookedClient.AddHook(hook)
+	defer hookedClient.Close()
+
+	manager := auth_manager.NewAuthManager(redisClient, opts)
+	hookedManager := auth_manager.NewAuthManager(hookedClient, opts)
+
+	generate := func(deviceID string) string {
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+			Meta:      &auth_manager.TokenMeta{DeviceID: deviceID},
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		return token
+	}
+	token := generate("first")
+
+	// The token is issued again in between the read of the touch and its write
+	hook.afterGet = func() {
+		generate("second")
+	}
+
+	require.NoError(s.T(), hookedManager.TouchToken(ctx, token))
+
+	decoded, err := manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "second", decoded.Meta.DeviceID)
+	require.False(s.T(), decoded.Meta.LastUsed.IsZero())
+
+	// The input is checked like by the decode methods
+	require.NoError(s.T(), manager.TouchToken(ctx, " "+token+"\n"))
+	require.ErrorIs(s.T(), manager.TouchToken(ctx, "short"), auth_manager.ErrInvalidToken)
+}
+
+// afterGetHook runs afterGet once, after the first GET which was sent to Redis with it set.
+type afterGetHook struct {
+	afterGet func()
+}
+
+func (h *afterGetHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *afterGetHook) AfterProcess(_ context.Context, cmd redis.Cmder) error {
+	if afterGet := h.afterGet; afterGet != nil && cmd.Name() == "get" {
+		h.afterGet = nil
+		afterGet()
+	}
+	return nil
+}
+
+func (h *afterGetHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *afterGetHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_DestroyPlainTokenExists() {
+	ctx := context.TODO()
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	existed, err := s.authManager.DestroyPlainTokenExists(ctx, token)
+	require.NoError(s.T(), err)
+	require.True(s.T(), existed)
+
+	existed, err = s.authManager.DestroyPlainTokenExists(ctx, token)
+	require.NoError(s.T(), err)
+	require.False(s.T(), existed)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeMalformedTokens() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	hook := &countingHook{}
+	countingClient := redis.NewClient(redisClient.Options())
+	countingClient.AddHook(hook)
+	defer countingClient.Close()
+
+	authManager := auth_manager.NewAuthManager(countingClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+	})
+
+	// Legitimately formatted tokens still pass
+	plainToken, err := authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.VerifyEmail,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = authManager.DecodePlainToken(ctx, plainToken, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	accessToken, err := authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = authManager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	// Junk is rejected without reaching Redis
+	commands := hook.commands.Load()
+	for _, junk := range []string{"", "short", plainToken + "x", plainToken[1:] + "!", "a.b", "a..c"} {
+		_, err = authManager.DecodePlainToken(ctx, junk, auth_manager.VerifyEmail)
+		require.Error(s.T(), err, junk)
+
+		_, err = authManager.DecodeRefreshToken(ctx, userUUID, junk)
+		require.Error(s.T(), err, junk)
+
+		_, err = authManager.DecodeAccessToken(ctx, junk)
+		require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken, junk)
+	}
+	require.Equal(s.T(), commands, hook.commands.Load())
+}
+
+func (s *AuthManagerTestSuite) Test_PlainTokenMaxAge() {
+	ctx := context.TODO()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		PlainTokenMaxAge: map[auth_manager.TokenType]time.Duration{
+			auth_manager.ResetPassword: time.Hour,
+		},
+	})
+
+	generate := func(tokenType auth_manager.TokenType, createdAt time.Time) string {
+		token, err := manager.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+			UUID:      uuid.NewString(),
+			CreatedAt: createdAt,
+		}, time.Hour*24)
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	fresh := generate(auth_manager.ResetPassword, time.Now())
+	_, err := manager.DecodePlainToken(ctx, fresh, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	// Still in Redis, but implausibly old for its type
+	stale := generate(auth_manager.ResetPassword, time.Now().Add(-time.Hour*2))
+	_, err = manager.DecodePlainToken(ctx, stale, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooOld)
+
+	_, err = manager.DecodeTokenAllowing(ctx, stale, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooOld)
+
+	// Types without a max age only depend on the Redis TTL
+	old := generate(auth_manager.VerifyEmail, time.Now().Add(-time.Hour*2))
+	_, err = manager.DecodePlainToken(ctx, old, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_TrackStatelessTokens() {
+	ctx := context.TODO()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:           "private-key",
+		StatelessTokenTypes:  []auth_manager.TokenType{auth_manager.ResetPassword},
+		TrackStatelessTokens: true,
+	})
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// The jwt alone is enough to find its Redis entry
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeTokenAllowing(ctx, token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	existed, err := manager.DestroyPlainTokenExists(ctx, token)
+	require.NoError(s.T(), err)
+	require.True(s.T(), existed)
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	existed, err = manager.DestroyPlainTokenExists(ctx, token)
+	require.NoError(s.T(), err)
+	require.False(s.T(), existed)
+}
+
+func (s *AuthManagerTestSuite) Test_DecodeTokensWithSurroundingWhitespace() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	plainToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	refreshToken, err := s.authManager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+	require.NoError(s.T(), err)
+
+	for _, pad := range []func(string) string{
+		func(token string) string { return " " + token + " " },
+		func(token string) string { return token + "\n" },
+		func(token string) string { return "\r\n\t" + token + "\r\n" },
+	} {
+		claims, err := s.authManager.DecodePlainToken(ctx, pad(plainToken), auth_manager.ResetPassword)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), userUUID, claims.UUID)
+
+		_, err = s.authManager.DecodeTokenAllowing(ctx, pad(plainToken), auth_manager.ResetPassword)
+		require.NoError(s.T(), err)
+
+		_, err = s.authManager.DecodeAccessToken(ctx, pad(accessToken))
+		require.NoError(s.T(), err)
+
+		_, err = s.authManager.DecodeRefreshToken(ctx, userUUID, pad(refreshToken))
+		require.NoError(s.T(), err)
+	}
+
+	// Whitespace inside a token is not dropped
+	_, err = s.authManager.DecodePlainToken(ctx, plainToken[:10]+" "+plainToken[10:], auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
+
+func (s *AuthManagerTestSuite) Test_AcceptOnRedisMiss() {
+	ctx := context.TODO()
+
+	for _, acceptOnRedisMiss := range []bool{false, true} {
+		manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:           "private-key",
+			StatelessTokenTypes:  []auth_manager.TokenType{auth_manager.ResetPassword},
+			TrackStatelessTokens: true,
+			AcceptOnRedisMiss:    acceptOnRedisMiss,
+		})
+
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+			UUID:      uuid.NewString(),
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		// Drop the Redis entry behind the manager's back, as an eviction would
+		keys, err := redisClient.Keys(ctx, "stateless_token:*").Result()
+		require.NoError(s.T(), err)
+		require.NotEmpty(s.T(), keys)
+		for _, key := range keys {
+			require.NoError(s.T(), redisClient.Del(ctx, key).Err())
+		}
+
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+		if !acceptOnRedisMiss {
+			require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+			continue
+		}
+		require.NoError(s.T(), err)
+
+		// Destroying the token still logs it out through the revocation of its jti
+		existed, err := manager.DestroyPlainTokenExists(ctx, token)
+		require.NoError(s.T(), err)
+		require.True(s.T(), existed)
+
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+		require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+	}
+}
+
+func (s *AuthManagerTestSuite) Test_UUIDNormalizer() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		UUIDNormalizer: func(id string) (string, error) {
+			return strings.ToLower(id), nil
+		},
+	})
+
+	payload := &auth_manager.TokenPayload{
+		UUID:      strings.ToUpper(userUUID),
+		CreatedAt: time.Now(),
+	}
+	upperToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), strings.ToUpper(userUUID), payload.UUID)
+
+	lowerToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Both tokens are indexed for the same user
+	members, err := redisClient.SMembers(ctx, "plain_token_index:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.ElementsMatch(s.T(), []string{upperToken, lowerToken}, members)
+
+	exists, err := redisClient.Exists(ctx, "plain_token_index:"+strings.ToUpper(userUUID)).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(0), exists)
+
+	claims, err := manager.DecodePlainToken(ctx, upperToken, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	// Lookups are normalized as well
+	activeTokens, err := manager.ListActiveTokens(ctx, strings.ToUpper(userUUID))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 2)
+
+	accessToken, err := manager.GenerateAccessToken(ctx, strings.ToUpper(userUUID), time.Minute)
+	require.NoError(s.T(), err)
+
+	accessClaims, err := manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, accessClaims.Payload.UUID)
+	require.Equal(s.T(), userUUID, accessClaims.Subject)
+
+	// An error of the normalizer rejects the uuid
+	manager = auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		UUIDNormalizer: func(id string) (string, error) {
+			return "", auth_manager.ErrMalformedUUID
+		},
+	})
+
+	_, err = manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrMalformedUUID)
+}
+
+func (s *AuthManagerTestSuite) Test_SoftDelete() {
+	ctx := context.TODO()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:            "private-key",
+		SoftDeleteGracePeriod: time.Millisecond * 200,
+	})
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	existed, err := manager.DestroyPlainTokenWithReason(ctx, token, "password changed")
+	require.NoError(s.T(), err)
+	require.True(s.T(), existed)
+
+	// The tombstone tells why the token stopped working
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+	require.Contains(s.T(), err.Error(), "password changed")
+
+	_, err = manager.DecodeTokenAllowing(ctx, token, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+
+	// Destroying it again doesn't replace the reason
+	existed, err = manager.DestroyPlainTokenExists(ctx, token)
+	require.NoError(s.T(), err)
+	require.False(s.T(), existed)
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.Contains(s.T(), err.Error(), "password changed")
+
+	// Once the grace period is over the token is simply gone
+	time.Sleep(time.Millisecond * 400)
+
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	// Without the option no tombstone is kept
+	token, err = s.authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DestroyPlainTokenWithReason(ctx, token, "password changed")
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
+
+func (s *AuthManagerTestSuite) Test_DeterministicPlainTokens() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:               "private-key",
+		DeterministicPlainTokens: true,
+	})
+
+	generate := func(tokenType auth_manager.TokenType, purpose string, deviceID string) string {
+		token, err := manager.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+			Purpose:   purpose,
+			Meta:      &auth_manager.TokenMeta{DeviceID: deviceID},
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		return token
+	}
+
+	first := generate(auth_manager.ResetPassword, "forgot-password", "first")
+	second := generate(auth_manager.ResetPassword, "forgot-password", "second")
+	require.Equal(s.T(), first, second)
+
+	// The latter overwrote the former
+	claims, err := manager.DecodePlainToken(ctx, first, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "second", claims.Meta.DeviceID)
+
+	activeTokens, err := manager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), activeTokens, 1)
+
+	// Any other uuid, type or purpose derives another token
+	require.NotEqual(s.T(), first, generate(auth_manager.ResetPassword, "admin-reset", "first"))
+	require.NotEqual(s.T(), first, generate(auth_manager.VerifyEmail, "forgot-password", "first"))
+
+	otherToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+		Purpose:   "forgot-password",
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), first, otherToken)
+
+	// The token can't be derived without the secret
+	otherManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:               "other-private-key",
+		DeterministicPlainTokens: true,
+	})
+	otherToken, err = otherManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+		Purpose:   "forgot-password",
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), first, otherToken)
+
+	// Reissuing keeps the token alive under the same key
+	reissued, err := manager.ReissueToken(ctx, first, time.Hour)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), first, reissued)
+
+	_, err = manager.DecodePlainToken(ctx, reissued, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+}
+
+type countingCodec struct {
+	auth_manager.Codec
+	unmarshals int
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return c.Codec.Unmarshal(data, v)
+}
+
+func (s *AuthManagerTestSuite) Test_EqualizeMissTiming() {
+	ctx := context.TODO()
+
+	for _, equalize := range []bool{true, false} {
+		codec := &countingCodec{Codec: auth_manager.JSONCodec}
+		manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			PrivateKey:         "private-key",
+			Codec:              codec,
+			EqualizeMissTiming: equalize,
+		})
+
+		token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      uuid.NewString(),
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), 1, codec.unmarshals)
+
+		err = manager.DestroyPlainToken(ctx, token)
+		require.NoError(s.T(), err)
+
+		// A missing token decodes the stand-in entry only with the option set
+		unmarshals := codec.unmarshals
+		_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+		if equalize {
+			require.Equal(s.T(), unmarshals+1, codec.unmarshals)
+		} else {
+			require.Equal(s.T(), unmarshals, codec.unmarshals)
+		}
+	}
+}
+
+// roundTripHook counts the round-trips to Redis, a pipeline being a single one.
+type roundTripHook struct {
+	roundTrips int
+}
+
+func (h *roundTripHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	h.roundTrips++
+	return ctx, nil
+}
+
+func (h *roundTripHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *roundTripHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	h.roundTrips++
+	return ctx, nil
+}
+
+func (h *roundTripHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_EqualizeMissTimingRoundTrips() {
+	ctx := context.TODO()
+
+	hook := &roundTripHook{}
+	client := redis.NewClient(redisClient.Options())
+	client.AddHook(hook)
+	defer client.Close()
+
+	manager := auth_manager.NewAuthManager(client, auth_manager.AuthManagerOpts{
+		PrivateKey:            "private-key",
+		EqualizeMissTiming:    true,
+		UserEpochs:            true,
+		SoftDeleteGracePeriod: time.Minute,
+	})
+
+	token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	roundTrips := func(token string) int {
+		before := hook.roundTrips
+		_, _ = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+		return hook.roundTrips - before
+	}
+
+	found := roundTrips(token)
+	require.Equal(s.T(), found, roundTrips(strings.Repeat("A", len(token))))
+
+	// A destroyed token is answered from its tombstone in the same round-trips
+	require.NoError(s.T(), manager.DestroyPlainToken(ctx, token))
+	_, err = manager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+	require.Equal(s.T(), found, roundTrips(token))
+}
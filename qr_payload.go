@@ -0,0 +1,62 @@
+package auth_manager
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// QRTokenParam is the query parameter carrying the token in the payloads of GenerateQRPayload.
+const QRTokenParam = "t"
+
+// The base64 alphabet of plain tokens maps one to one onto its url-safe variant, which needs no escaping in a url.
+var (
+	urlSafeToken   = strings.NewReplacer("+", "-", "/", "_")
+	urlUnsafeToken = strings.NewReplacer("-", "+", "_", "/")
+)
+
+// GenerateQRPayload generates a plain token like GeneratePlainToken and returns it in a url on baseURL, e.g. for
+// the QR code of a mobile pairing flow. The token is the QRTokenParam query parameter, written url-safe so the
+// payload stays compact. Rendering the QR code is left to the caller; DecodeQRPayload reads the payload back.
+func (t *authManager) GenerateQRPayload(ctx context.Context, baseURL string, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, error) {
+	qrURL, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := t.GeneratePlainToken(ctx, tokenType, payload, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	// Stateless tokens are jwt, whose segments are url-safe already.
+	if !isJWT(token) {
+		token = urlSafeToken.Replace(token)
+	}
+
+	query := qrURL.Query()
+	query.Set(QRTokenParam, token)
+	qrURL.RawQuery = query.Encode()
+
+	return qrURL.String(), nil
+}
+
+// DecodeQRPayload reads the token of a payload returned by GenerateQRPayload and decodes it like DecodePlainToken.
+// A payload without a token returns ErrTokenNotProvided.
+func (t *authManager) DecodeQRPayload(ctx context.Context, qrPayload string, tokenType TokenType) (*TokenPayload, error) {
+	qrURL, err := url.Parse(trimToken(qrPayload))
+	if err != nil {
+		return nil, t.disclose(ErrInvalidToken)
+	}
+
+	token := qrURL.Query().Get(QRTokenParam)
+	if token == "" {
+		return nil, ErrTokenNotProvided
+	}
+	if !isJWT(token) {
+		token = urlUnsafeToken.Replace(token)
+	}
+
+	return t.DecodePlainToken(ctx, token, tokenType)
+}
@@ -0,0 +1,57 @@
+package auth_manager_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_QRPayload() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	qrPayload, err := s.authManager.GenerateQRPayload(ctx, "https://example.com/pair?device=tv", auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// The token needs no escaping and the query of the base url is kept
+	require.True(s.T(), strings.HasPrefix(qrPayload, "https://example.com/pair?"))
+	require.NotContains(s.T(), qrPayload, "%")
+	qrURL, err := url.Parse(qrPayload)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "tv", qrURL.Query().Get("device"))
+
+	claims, err := s.authManager.DecodeQRPayload(ctx, qrPayload, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	_, err = s.authManager.DecodeQRPayload(ctx, qrPayload, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	_, err = s.authManager.DecodeQRPayload(ctx, "https://example.com/pair", auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenNotProvided)
+
+	// Stateless tokens are carried as they are
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+	})
+
+	qrPayload, err = manager.GenerateQRPayload(ctx, "https://example.com/pair", auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err = manager.DecodeQRPayload(ctx, qrPayload, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+}
@@ -5,16 +5,12 @@ import (
 	"encoding/base64"
 )
 
-var randomBytesPool = make([]byte, 1024)
-
+// generateRandomString reads into a buffer of its own, so concurrent callers never share random bytes.
 func generateRandomString(length int) (string, error) {
-	if length > len(randomBytesPool) {
-		randomBytesPool = make([]byte, length)
-	}
-
-	if _, err := rand.Read(randomBytesPool[:length]); err != nil {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
-	return base64.RawStdEncoding.EncodeToString(randomBytesPool[:length]), nil
+	return base64.RawStdEncoding.EncodeToString(b), nil
 }
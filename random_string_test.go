@@ -1,9 +1,44 @@
 package auth_manager
 
-import "testing"
+import (
+	"sync"
+	"testing"
+)
 
 func BenchmarkGenerateRandomString(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		generateRandomString(32)
 	}
 }
+
+func TestGenerateRandomStringConcurrentlyUnique(t *testing.T) {
+	const goroutines, perGoroutine = 64, 1000
+
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				s, err := generateRandomString(TokenByteLength)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results[g] = append(results[g], s)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]struct{}, goroutines*perGoroutine)
+	for _, strings := range results {
+		for _, s := range strings {
+			if _, ok := seen[s]; ok {
+				t.Fatalf("duplicate random string %q", s)
+			}
+			seen[s] = struct{}{}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package auth_manager
+
+// RedisUnavailablePolicy decides how DecodeAccessToken behaves when the Redis checks of an access token,
+// the jti revocation and the session, fail because Redis can't be reached. A key which is simply missing
+// is not an outage and is never affected by the policy.
+type RedisUnavailablePolicy int
+
+const (
+	// FailClosed rejects the token with the Redis error. It is the default.
+	FailClosed RedisUnavailablePolicy = iota
+
+	// FailOpen accepts the token on its signature and expiration alone. During an outage a revoked token,
+	// or one whose session was destroyed, is accepted until it expires, so only use it with short lived tokens.
+	FailOpen
+)
+
+// failOpen reports whether a failed Redis check of an access token is skipped.
+func (t *authManager) failOpen() bool {
+	return t.opts.RedisUnavailablePolicy == FailOpen
+}
@@ -0,0 +1,88 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var errRedisOutage = errors.New("simulated redis outage")
+
+// outageHook fails every command while down is set.
+type outageHook struct {
+	down bool
+}
+
+func (h *outageHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	if h.down {
+		return ctx, errRedisOutage
+	}
+	return ctx, nil
+}
+
+func (h *outageHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *outageHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	if h.down {
+		return ctx, errRedisOutage
+	}
+	return ctx, nil
+}
+
+func (h *outageHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_RedisUnavailablePolicy() {
+	ctx := context.TODO()
+
+	hook := &outageHook{}
+	client := redis.NewClient(redisClient.Options())
+	client.AddHook(hook)
+	defer client.Close()
+
+	newManager := func(policy auth_manager.RedisUnavailablePolicy) auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(client, auth_manager.AuthManagerOpts{
+			PrivateKey:             "private-key",
+			RedisUnavailablePolicy: policy,
+		})
+	}
+	failClosed := newManager(auth_manager.FailClosed)
+	failOpen := newManager(auth_manager.FailOpen)
+
+	token, err := failClosed.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	revokedToken, err := failClosed.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+	revokedClaims, err := failClosed.DecodeAccessToken(ctx, revokedToken)
+	require.NoError(s.T(), err)
+	err = failClosed.RevokeByJTI(ctx, revokedClaims.ID)
+	require.NoError(s.T(), err)
+
+	hook.down = true
+
+	_, err = failClosed.DecodeAccessToken(ctx, token)
+	require.ErrorIs(s.T(), err, errRedisOutage)
+
+	_, err = failOpen.DecodeAccessToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	// The signature and expiration are still checked
+	_, err = failOpen.DecodeAccessToken(ctx, token[:len(token)-2])
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	hook.down = false
+
+	// A revocation which Redis can answer is never bypassed
+	_, err = failOpen.DecodeAccessToken(ctx, revokedToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+}
@@ -2,67 +2,149 @@ package auth_manager
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 const refreshTokenByteLength = 32
 
-func generateHashKey(uuid string) string {
-	return fmt.Sprintf("refresh_token:%s", uuid)
-}
-
 type RefreshTokenPayload struct {
 	IPAddress  string        `json:"ipAddress"`
 	UserAgent  string        `json:"userAgent"`
 	LoggedInAt time.Duration `json:"loggedInAt"`
+
+	// AccessTokenID is the jti of the access token issued along with the refresh token by GenerateTokenPair.
+	AccessTokenID string `json:"accessTokenId,omitempty"`
 }
 
 // The GenerateRefreshToken method generates a random string with base64 with a static byte length
-// and stores it in the Redis store with provided expiration duration.
+// and stores it in the Redis store with provided expiration duration. The refresh tokens of a user share a hash,
+// which lives as long as the latest expiring of them.
 func (t *authManager) GenerateRefreshToken(ctx context.Context, uuid string, payload *RefreshTokenPayload, expiresAt time.Duration) (string, error) {
+	if err := t.opts.checkAllowedTokenType(RefreshToken); err != nil {
+		return "", err
+	}
+
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", err
+	}
+
+	uuid, err := t.opts.validateUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt, err = t.clampExpiry(ctx, uuid, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
 	// Generate random string
 	refreshToken, err := generateRandomString(refreshTokenByteLength)
 	if err != nil {
 		return "", err
 	}
 
-	payloadJson, err := json.Marshal(payload)
+	encodedPayload, err := t.codec.Marshal(payload)
 	if err != nil {
 		return "", ErrEncodingPayload
 	}
 
-	err = t.redisClient.HSet(ctx, generateHashKey(uuid), []string{
-		refreshToken, string(payloadJson),
-	}).Err()
+	_, err = t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, t.keys.generateHashKey(uuid), []string{
+			refreshToken, string(encodedPayload),
+		})
+		extendTTLScript.Eval(ctx, pipe, []string{t.keys.generateHashKey(uuid)}, expiresAt.Milliseconds())
+		pipe.SAdd(ctx, t.keys.activeUsersKey(), uuid)
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
 
+	t.audit(ctx, AuditEntry{Event: AuditIssued, UUID: uuid, TokenType: RefreshToken})
+
 	return refreshToken, nil
 }
 
 func (t *authManager) DecodeRefreshToken(ctx context.Context, uuid string, token string) (*RefreshTokenPayload, error) {
-	payloadStr, err := t.redisClient.HGet(ctx, generateHashKey(uuid), token).Result()
+	payload, err := t.decodeRefreshToken(ctx, uuid, token)
+	return payload, t.disclose(err)
+}
+
+func (t *authManager) decodeRefreshToken(ctx context.Context, uuid string, token string) (*RefreshTokenPayload, error) {
+	uuid, err := t.opts.normalizeUUID(uuid)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, err
 	}
 
-	var payload *RefreshTokenPayload
+	token = trimToken(token)
+	if err := t.checkTokenSize(token); err != nil {
+		return nil, err
+	}
+	if isJWT(token) {
+		return nil, ErrUnsupportedTokenType
+	}
+	if !validOpaqueFormat(token, refreshTokenLength) {
+		return nil, ErrInvalidToken
+	}
 
-	err = json.Unmarshal([]byte(payloadStr), &payload)
+	payloadStr, err := t.redisClient.HGet(ctx, t.keys.generateHashKey(uuid), token).Result()
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
 
+	var payload *RefreshTokenPayload
+
+	err = t.codec.Unmarshal([]byte(payloadStr), &payload)
+	if err != nil || payload == nil {
+		return nil, ErrCorruptedEntry
+	}
+
 	return payload, nil
 }
 
 func (t *authManager) TerminateRefreshTokens(ctx context.Context, uuid string) error {
-	return t.redisClient.Del(ctx, generateHashKey(uuid)).Err()
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.terminateRefreshTokens(ctx, uuid)
+	return err
+}
+
+// terminateRefreshTokens deletes the refresh token hash of the normalized uuid and reports whether it existed.
+func (t *authManager) terminateRefreshTokens(ctx context.Context, uuid string) (bool, error) {
+	deleted, err := t.redisClient.Del(ctx, t.keys.generateHashKey(uuid)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if deleted > 0 {
+		t.audit(ctx, AuditEntry{Event: AuditRevoked, UUID: uuid, TokenType: RefreshToken})
+	}
+
+	_, err = t.pruneActiveUser(ctx, uuid)
+	return deleted > 0, err
 }
 
 func (t *authManager) RemoveRefreshToken(ctx context.Context, uuid string, token string) error {
-	return t.redisClient.HDel(ctx, generateHashKey(uuid), token).Err()
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := t.redisClient.HDel(ctx, t.keys.generateHashKey(uuid), token).Result()
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		t.audit(ctx, AuditEntry{Event: AuditRevoked, UUID: uuid, TokenType: RefreshToken})
+	}
+
+	_, err = t.pruneActiveUser(ctx, uuid)
+	return err
 }
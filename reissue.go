@@ -0,0 +1,108 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReissueToken validates the token and issues a new one expiring after expiresAt which keeps the lineage of the
+// original: an access token keeps its jti, iat and CreatedAt, a plain token keeps its claims and CreatedAt and is
+// moved to a fresh key, the old one being destroyed.
+//
+// An old access token can't be invalidated on its own since revoking its jti would also revoke the reissued token,
+// it stays valid until it expires. Stateless plain tokens can't be reissued and return ErrUnsupportedTokenType.
+func (t *authManager) ReissueToken(ctx context.Context, token string, expiresAt time.Duration) (string, error) {
+	if err := t.checkTokenSize(token); err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", err
+	}
+	if isJWT(token) {
+		return t.reissueAccessToken(ctx, token, expiresAt)
+	}
+
+	return t.reissuePlainToken(ctx, token, expiresAt)
+}
+
+func (t *authManager) reissueAccessToken(ctx context.Context, token string, expiresAt time.Duration) (string, error) {
+	if err := t.opts.checkAllowedTokenType(AccessToken); err != nil {
+		return "", err
+	}
+
+	claims, err := t.DecodeAccessToken(ctx, token)
+	if err != nil {
+		if errors.Is(FailureReason(err), ErrInvalidTokenType) {
+			return "", ErrUnsupportedTokenType
+		}
+
+		return "", err
+	}
+
+	expiresAt, err = t.clampExpiry(ctx, claims.Payload.UUID, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	reissued := t.newAccessTokenClaims(claims.Payload, claims.ID, issuedAt(claims), time.Now().Add(expiresAt))
+	reissued.SessionID = claims.SessionID
+	reissued.Audience = claims.Audience
+
+	return t.signToken(AccessToken, reissued.jwtClaims())
+}
+
+func (t *authManager) reissuePlainToken(ctx context.Context, token string, expiresAt time.Duration) (string, error) {
+	if !validOpaqueFormat(token, plainTokenLength) {
+		return "", ErrInvalidToken
+	}
+
+	claims, err := t.decodeStoredToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.opts.checkAllowedTokenType(claims.TokenType); err != nil {
+		return "", err
+	}
+
+	expiresAt, err = t.clampExpiry(ctx, claims.UUID, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	newToken, err := t.newPlainToken(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encodedClaims, err := t.encodePayload(claims)
+	if err != nil {
+		return "", ErrEncodingPayload
+	}
+
+	indexKey := t.keys.generateIndexKey(claims.UUID)
+
+	// The new token replaces the old one atomically, so exactly one of them is valid at any time.
+	// A deterministic token is derived again as the same token, which only gets the new expiration.
+	_, err = t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, t.keys.generatePlainTokenKey(newToken), encodedClaims, expiresAt)
+		pipe.SAdd(ctx, indexKey, newToken)
+		extendTTLScript.Eval(ctx, pipe, []string{indexKey}, expiresAt.Milliseconds())
+		if newToken != token {
+			pipe.Del(ctx, t.keys.generatePlainTokenKey(token))
+			pipe.SRem(ctx, indexKey, token)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	t.cache.evict(token)
+	t.publishInvalidation(ctx, invalidationMessage{TokenHashes: []string{HashToken(token)}})
+
+	return newToken, nil
+}
@@ -0,0 +1,75 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ReissueAccessToken() {
+	ctx := context.TODO()
+
+	token, err := s.authManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := s.authManager.DecodeAccessToken(ctx, token)
+	require.NoError(s.T(), err)
+
+	reissued, err := s.authManager.ReissueToken(ctx, token, time.Hour)
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), token, reissued)
+
+	reissuedClaims, err := s.authManager.DecodeAccessToken(ctx, reissued)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), claims.ID, reissuedClaims.ID)
+	require.True(s.T(), claims.Payload.CreatedAt.Equal(reissuedClaims.Payload.CreatedAt))
+	require.Equal(s.T(), claims.IssuedAt, reissuedClaims.IssuedAt)
+	require.True(s.T(), reissuedClaims.ExpiresAt.After(claims.ExpiresAt))
+
+	// Revoking the jti revokes the whole lineage
+	err = s.authManager.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.ReissueToken(ctx, reissued, time.Hour)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+}
+
+func (s *AuthManagerTestSuite) Test_ReissuePlainToken() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	createdAt := time.Now().Add(-time.Hour)
+
+	token, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: createdAt,
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	reissued, err := s.authManager.ReissueToken(ctx, token, time.Hour)
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), token, reissued)
+
+	claims, err := s.authManager.DecodePlainToken(ctx, reissued, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+	require.True(s.T(), createdAt.Equal(claims.CreatedAt))
+
+	ttl, err := redisClient.TTL(ctx, reissued).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, time.Minute)
+
+	// The old key is gone, from Redis and from the owner's index
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	index, err := redisClient.SMembers(ctx, "plain_token_index:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{reissued}, index)
+
+	_, err = s.authManager.ReissueToken(ctx, token, time.Hour)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+}
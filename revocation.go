@@ -0,0 +1,75 @@
+package auth_manager
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultRevocationTTL is how long a revoked jti is remembered when AuthManagerOpts.RevocationTTL is not set.
+const defaultRevocationTTL = time.Hour * 24 * 7
+
+func (t *authManager) revocationTTL() time.Duration {
+	if t.opts.RevocationTTL > 0 {
+		return t.opts.RevocationTTL
+	}
+
+	return defaultRevocationTTL
+}
+
+// RevokeByJTI marks the access tokens with the given jti values as revoked.
+// Each jti is remembered for RevocationTTL, which must cover the lifetime of the longest living access token.
+func (t *authManager) RevokeByJTI(ctx context.Context, jtis ...string) error {
+	if len(jtis) == 0 {
+		return nil
+	}
+
+	ttl := t.revocationTTL()
+
+	_, err := t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, jti := range jtis {
+			pipe.Set(ctx, t.keys.generateRevocationKey(jti), 1, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t.auditRevokedJTIs(ctx, jtis)
+
+	t.evictJTIs(jtis)
+	t.publishInvalidation(ctx, invalidationMessage{JTIs: jtis})
+
+	return nil
+}
+
+// evictJTIs removes the cached access tokens with the given jti values.
+func (t *authManager) evictJTIs(jtis []string) {
+	t.cache.evictFunc(func(value interface{}) bool {
+		claims, ok := value.(AccessTokenClaims)
+		return ok && slices.Contains(jtis, claims.ID)
+	})
+}
+
+// IsRevoked reports whether the jti was revoked with RevokeByJTI, in a single Redis round-trip and without parsing
+// the token, for callers which cache the claims of decoded tokens themselves. Listening with SubscribeInvalidations
+// tells about revocations as they happen, so the poll can be kept to the tokens which were not seen yet.
+func (t *authManager) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	return t.isRevoked(ctx, jti)
+}
+
+func (t *authManager) isRevoked(ctx context.Context, jti string) (bool, error) {
+	count, err := t.redisClient.Exists(ctx, t.keys.generateRevocationKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,132 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// serviceSubjectPrefix marks the sub claim of service tokens, so jwt consumers can tell them from user tokens too.
+const serviceSubjectPrefix = "service:"
+
+// ServiceTokenClaims are the claims of a service token, issued for a machine client rather than a user.
+type ServiceTokenClaims struct {
+	ServiceID string
+	Scopes    []string
+
+	// ID is the jti claim, the id RevokeByJTI takes.
+	ID        string
+	Issuer    string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// serviceTokenClaims is the jwt form of ServiceTokenClaims. The payload only carries the token type, service
+// tokens have no user.
+type serviceTokenClaims struct {
+	Payload TokenPayload
+	Scopes  []string `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateServiceToken signs a token for a machine client, such as a cron job or another service, which has no
+// user uuid. Service tokens are of the ServiceToken type, so they are rejected by DecodeAccessToken and every
+// other decode method which expects a user token, and can be revoked with RevokeByJTI.
+func (t *authManager) GenerateServiceToken(ctx context.Context, serviceID string, scopes []string, expiresAt time.Duration) (string, error) {
+	if err := t.opts.checkAllowedTokenType(ServiceToken); err != nil {
+		return "", err
+	}
+	if err := t.validateOpts(); err != nil {
+		return "", err
+	}
+	if serviceID == "" {
+		return "", ErrEmptyServiceID
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", err
+	}
+
+	jti, err := generateRandomString(jtiByteLength)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := serviceTokenClaims{
+		Payload: TokenPayload{
+			TokenType: ServiceToken,
+			CreatedAt: now,
+		},
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   serviceSubjectPrefix + serviceID,
+			Issuer:    "go-auth-manager",
+			Audience:  t.opts.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresAt)),
+		},
+	}
+
+	return t.signToken(ServiceToken, claims)
+}
+
+// DecodeServiceToken verifies a token issued by GenerateServiceToken: its signature and expiration, its type,
+// MaxExpiryHorizon and the revocation of its jti. User tokens are rejected with ErrInvalidTokenType.
+func (t *authManager) DecodeServiceToken(ctx context.Context, token string) (*ServiceTokenClaims, error) {
+	claims, err := t.decodeServiceToken(ctx, token)
+	return claims, t.disclose(err)
+}
+
+func (t *authManager) decodeServiceToken(ctx context.Context, token string) (*ServiceTokenClaims, error) {
+	token = trimToken(token)
+	if err := t.checkTokenSize(token); err != nil {
+		return nil, err
+	}
+	if !validJWTFormat(token) {
+		return nil, ErrInvalidToken
+	}
+
+	parsedClaims := &serviceTokenClaims{}
+	_, err := t.parseWithClaims(token, parsedClaims, t.keyFunc)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	if parsedClaims.Payload.TokenType != ServiceToken {
+		return nil, ErrInvalidTokenType
+	}
+	serviceID, ok := strings.CutPrefix(parsedClaims.Subject, serviceSubjectPrefix)
+	if !ok || serviceID == "" {
+		return nil, ErrInvalidToken
+	}
+	if t.beyondExpiryHorizon(parsedClaims.ExpiresAt.Time) {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := t.isRevoked(ctx, parsedClaims.ID)
+	if err != nil && !t.failOpen() {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	claims := &ServiceTokenClaims{
+		ServiceID: serviceID,
+		Scopes:    parsedClaims.Scopes,
+		ID:        parsedClaims.ID,
+		Issuer:    parsedClaims.Issuer,
+		Audience:  parsedClaims.Audience,
+		ExpiresAt: parsedClaims.ExpiresAt.Time,
+	}
+	if parsedClaims.IssuedAt != nil {
+		claims.IssuedAt = parsedClaims.IssuedAt.Time
+	}
+
+	return claims, nil
+}
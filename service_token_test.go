@@ -0,0 +1,58 @@
+package auth_manager_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ServiceToken() {
+	ctx := context.TODO()
+
+	token, err := s.authManager.GenerateServiceToken(ctx, "billing-cron", []string{"invoices:write"}, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := s.authManager.DecodeServiceToken(ctx, token)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "billing-cron", claims.ServiceID)
+	require.Equal(s.T(), []string{"invoices:write"}, claims.Scopes)
+	require.NotEmpty(s.T(), claims.ID)
+	require.WithinDuration(s.T(), time.Now().Add(time.Minute), claims.ExpiresAt, 2*time.Second)
+
+	// The subject tells other jwt consumers it is not a user
+	rawClaims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, rawClaims)
+	require.NoError(s.T(), err)
+	require.True(s.T(), strings.HasPrefix(rawClaims["sub"].(string), "service:"))
+
+	// It is rejected where a user token is required, and the other way around
+	_, err = s.authManager.DecodeAccessToken(ctx, token)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+	_, err = s.authManager.DecodeTokenAllowing(ctx, token, auth_manager.AccessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+	_, err = s.authManager.DecodeServiceToken(ctx, accessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	// Service tokens are revoked by jti
+	err = s.authManager.RevokeByJTI(ctx, claims.ID)
+	require.NoError(s.T(), err)
+	_, err = s.authManager.DecodeServiceToken(ctx, token)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+
+	_, err = s.authManager.GenerateServiceToken(ctx, "", nil, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrEmptyServiceID)
+	_, err = s.authManager.GeneratePlainToken(ctx, auth_manager.ServiceToken, &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+}
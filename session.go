@@ -0,0 +1,102 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const sessionIDByteLength = 32
+
+// CreateSession stores a server side session of the user living for expiresAt and returns its id.
+// Short lived access tokens are then issued for the session with GenerateSessionAccessToken.
+func (t *authManager) CreateSession(ctx context.Context, uuid string, expiresAt time.Duration) (string, error) {
+	uuid, err := t.opts.validateUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+	if err := validateExpiry(expiresAt); err != nil {
+		return "", err
+	}
+
+	expiresAt, err = t.clampExpiry(ctx, uuid, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID, err := generateRandomString(sessionIDByteLength)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.redisClient.Set(ctx, t.keys.generateSessionKey(sessionID), uuid, expiresAt).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return sessionID, nil
+}
+
+// GenerateSessionAccessToken issues an access token for the owner of the session, carrying the session id in its sid claim.
+func (t *authManager) GenerateSessionAccessToken(ctx context.Context, sessionID string, expiresAt time.Duration) (string, error) {
+	uuid, err := t.redisClient.Get(ctx, t.keys.generateSessionKey(sessionID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrSessionExpired
+	}
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt, err = t.clampExpiry(ctx, uuid, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	token, jti, err := t.generateAccessToken(ctx, uuid, sessionID, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.recordAccessToken(ctx, uuid, jti, expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// DestroySession removes the session, so its access tokens are rejected when VerifySession is set.
+func (t *authManager) DestroySession(ctx context.Context, sessionID string) error {
+	err := t.redisClient.Del(ctx, t.keys.generateSessionKey(sessionID)).Err()
+	if err != nil {
+		return err
+	}
+
+	t.evictSession(sessionID)
+	t.publishInvalidation(ctx, invalidationMessage{SessionIDs: []string{sessionID}})
+
+	return nil
+}
+
+// evictSession removes the cached access tokens of the session.
+func (t *authManager) evictSession(sessionID string) {
+	t.cache.evictFunc(func(value interface{}) bool {
+		claims, ok := value.(AccessTokenClaims)
+		return ok && claims.SessionID == sessionID
+	})
+}
+
+func (t *authManager) sessionAlive(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	count, err := t.redisClient.Exists(ctx, t.keys.generateSessionKey(sessionID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,53 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_Sessions() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:    "private-key",
+		VerifySession: true,
+	})
+
+	sessionID, err := manager.CreateSession(ctx, userUUID, time.Hour)
+	require.NoError(s.T(), err)
+
+	token, err := manager.GenerateSessionAccessToken(ctx, sessionID, time.Minute*5)
+	require.NoError(s.T(), err)
+
+	// Live session: accepted
+	claims, err := manager.DecodeAccessToken(ctx, token)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.Payload.UUID)
+	require.Equal(s.T(), sessionID, claims.SessionID)
+
+	// Tokens without a session can't be revoked server side and are rejected
+	sessionless, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, sessionless)
+	require.ErrorIs(s.T(), err, auth_manager.ErrSessionExpired)
+
+	// Destroyed session: the still unexpired token is rejected
+	err = manager.DestroySession(ctx, sessionID)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, token)
+	require.ErrorIs(s.T(), err, auth_manager.ErrSessionExpired)
+
+	_, err = manager.GenerateSessionAccessToken(ctx, sessionID, time.Minute*5)
+	require.ErrorIs(s.T(), err, auth_manager.ErrSessionExpired)
+
+	// Without VerifySession only the signature and expiration count
+	_, err = s.authManager.DecodeAccessToken(ctx, token)
+	require.NoError(s.T(), err)
+}
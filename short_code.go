@@ -0,0 +1,124 @@
+package auth_manager
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultShortCodeLength is the number of digits of short codes when AuthManagerOpts.ShortCodeLength is not set.
+const defaultShortCodeLength = 6
+
+// maxShortCodeAttempts bounds the draws of a free short code before GenerateTokenWithShortCode gives up.
+const maxShortCodeAttempts = 10
+
+func (t *authManager) shortCodeLength() int {
+	if t.opts.ShortCodeLength > 0 {
+		return t.opts.ShortCodeLength
+	}
+
+	return defaultShortCodeLength
+}
+
+// GenerateTokenWithShortCode generates a plain token like GeneratePlainToken along with a numeric short code which
+// resolves to the same token, e.g. a link token in an email and a code to type in by hand. The code is drawn until
+// one is found which no live token uses, or fails with ErrShortCodeExhausted. With only a few digits a code is
+// easily guessed, so the endpoint of DecodeByShortCode must be rate limited by the caller.
+func (t *authManager) GenerateTokenWithShortCode(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (string, string, error) {
+	token, err := t.GeneratePlainToken(ctx, tokenType, payload, expiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	code, err := t.storeShortCode(ctx, token, expiresAt)
+	if err != nil {
+		// A token without its code is best-effort removed, the caller only gets the error.
+		_ = t.DestroyPlainToken(ctx, token)
+		return "", "", err
+	}
+
+	return token, code, nil
+}
+
+func (t *authManager) storeShortCode(ctx context.Context, token string, expiresAt time.Duration) (string, error) {
+	length := t.shortCodeLength()
+	bound := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+
+	for attempt := 0; attempt < maxShortCodeAttempts; attempt++ {
+		n, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			return "", err
+		}
+		code := fmt.Sprintf("%0*d", length, n)
+
+		stored, err := t.redisClient.SetNX(ctx, t.keys.generateShortCodeKey(code), token, expiresAt).Result()
+		if err != nil {
+			return "", err
+		}
+		if stored {
+			return code, nil
+		}
+	}
+
+	return "", ErrShortCodeExhausted
+}
+
+// DecodeByShortCode decodes the token behind a short code of GenerateTokenWithShortCode like DecodePlainToken.
+// Codes are single use: a successful decode consumes both the code and its token, so neither works again, and once
+// the token is destroyed, e.g. after following the link, the code returns ErrTokenExpired. Concurrent decodes of a
+// code succeed only once.
+func (t *authManager) DecodeByShortCode(ctx context.Context, code string, tokenType TokenType) (*TokenPayload, error) {
+	code = trimToken(code)
+	if !validShortCode(code, t.shortCodeLength()) {
+		return nil, t.disclose(ErrInvalidToken)
+	}
+
+	codeKey := t.keys.generateShortCodeKey(code)
+	token, err := t.redisClient.Get(ctx, codeKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, t.disclose(ErrTokenExpired)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := t.DecodePlainToken(ctx, token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the decode which deletes the code consumes it.
+	deleted, err := t.redisClient.Del(ctx, codeKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, t.disclose(ErrTokenExpired)
+	}
+
+	err = t.DestroyPlainToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func validShortCode(code string, length int) bool {
+	if len(code) != length {
+		return false
+	}
+
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_GenerateTokenWithShortCode() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	generate := func(manager auth_manager.AuthManager) (string, string, error) {
+		return manager.GenerateTokenWithShortCode(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+	}
+
+	token, code, err := generate(s.authManager)
+	require.NoError(s.T(), err)
+	require.Regexp(s.T(), "^[0-9]{6}$", code)
+
+	linkClaims, err := s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+
+	// A code of another type is rejected without being consumed
+	_, err = s.authManager.DecodeByShortCode(ctx, code, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	codeClaims, err := s.authManager.DecodeByShortCode(ctx, code, auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), linkClaims, codeClaims)
+
+	// Both the code and the token are consumed
+	_, err = s.authManager.DecodeByShortCode(ctx, code, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+	_, err = s.authManager.DecodePlainToken(ctx, token, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	// Consuming the token through the link leaves the code with nothing to resolve
+	token, code, err = generate(s.authManager)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.authManager.DestroyPlainToken(ctx, token))
+
+	_, err = s.authManager.DecodeByShortCode(ctx, code, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenExpired)
+
+	_, err = s.authManager.DecodeByShortCode(ctx, "12ab", auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
+
+func (s *AuthManagerTestSuite) Test_ShortCodeCollisions() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:      "private-key",
+		KeyPrefix:       uuid.NewString(),
+		ShortCodeLength: 1,
+	})
+
+	// Codes are never handed out twice, until no free one is found
+	codes := map[string]struct{}{}
+	var err error
+	for i := 0; i < 100; i++ {
+		var code string
+		_, code, err = manager.GenerateTokenWithShortCode(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+			UUID:      userUUID,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		if errors.Is(err, auth_manager.ErrShortCodeExhausted) {
+			break
+		}
+		require.NoError(s.T(), err)
+
+		require.NotContains(s.T(), codes, code)
+		codes[code] = struct{}{}
+	}
+	require.ErrorIs(s.T(), err, auth_manager.ErrShortCodeExhausted)
+	require.LessOrEqual(s.T(), len(codes), 10)
+
+	// The token of a failed generation was removed
+	tokens, err := manager.ListActiveTokens(ctx, userUUID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), tokens, len(codes))
+}
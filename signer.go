@@ -0,0 +1,67 @@
+package auth_manager
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer delegates signing and verification of jwt to an external key provider such as a KMS or Vault,
+// so the key never has to be loaded into the process. When set in AuthManagerOpts it replaces PrivateKey.
+type Signer interface {
+	// Alg is the jwt "alg" header of the produced signatures, e.g. RS256 for an RSA key held by a KMS.
+	Alg() string
+	Sign(data []byte) ([]byte, error)
+	Verify(data []byte, signature []byte) error
+}
+
+// signerMethod adapts a Signer to a jwt.SigningMethod.
+type signerMethod struct {
+	signer Signer
+}
+
+func (m signerMethod) Alg() string {
+	return m.signer.Alg()
+}
+
+func (m signerMethod) Sign(signingString string, _ interface{}) ([]byte, error) {
+	return m.signer.Sign([]byte(signingString))
+}
+
+func (m signerMethod) Verify(signingString string, signature []byte, _ interface{}) error {
+	return m.signer.Verify([]byte(signingString), signature)
+}
+
+// signerFor returns the Signer of tokens of the type, its entry of TokenSigners or else the Signer, nil for PrivateKey.
+func (t *authManager) signerFor(tokenType TokenType) Signer {
+	if signer, ok := t.opts.TokenSigners[tokenType]; ok {
+		return signer
+	}
+
+	return t.opts.Signer
+}
+
+// claimsTokenType returns the token type claimed by a parsed, not yet verified, token so keyFunc can pick its Signer.
+func claimsTokenType(claims jwt.Claims) TokenType {
+	switch claims := claims.(type) {
+	case *accessTokenClaims:
+		return claims.Payload.TokenType
+	case *statelessTokenClaims:
+		return claims.Payload.TokenType
+	case *serviceTokenClaims:
+		return claims.Payload.TokenType
+	}
+
+	return -1
+}
+
+// signToken signs the claims of a token of the type with its Signer, or with PrivateKey and TokenEncodingAlgorithm.
+func (t *authManager) signToken(tokenType TokenType, claims jwt.Claims) (string, error) {
+	if signer := t.signerFor(tokenType); signer != nil {
+		return jwt.NewWithClaims(signerMethod{signer}, claims).SignedString(nil)
+	}
+
+	t.keysMu.RLock()
+	signingKey := t.signingKey
+	t.keysMu.RUnlock()
+
+	return jwt.NewWithClaims(TokenEncodingAlgorithm, claims).SignedString(signingKey)
+}
@@ -0,0 +1,166 @@
+package auth_manager_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMSSigner stands in for a KMS client holding an ed25519 key.
+type fakeKMSSigner struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	signs      atomic.Int64
+	verifies   atomic.Int64
+}
+
+func (s *fakeKMSSigner) Alg() string {
+	return "EdDSA"
+}
+
+func (s *fakeKMSSigner) Sign(data []byte) ([]byte, error) {
+	s.signs.Add(1)
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+func (s *fakeKMSSigner) Verify(data []byte, signature []byte) error {
+	s.verifies.Add(1)
+	if !ed25519.Verify(s.publicKey, data, signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (s *AuthManagerTestSuite) Test_Signer() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(s.T(), err)
+	signer := &fakeKMSSigner{privateKey: privateKey, publicKey: publicKey}
+
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		Signer:              signer,
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.ResetPassword},
+	})
+
+	token, err := authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), signer.signs.Load())
+
+	decoded, err := authManager.DecodeAccessToken(ctx, token)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, decoded.Payload.UUID)
+	require.Equal(s.T(), int64(1), signer.verifies.Load())
+
+	// Standard jwt tooling can verify the token with the public key
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	require.NoError(s.T(), err)
+
+	resetToken, err := authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		TokenType: auth_manager.ResetPassword,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = authManager.DecodePlainToken(ctx, resetToken, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	// HMAC tokens are rejected by a Signer backed manager and the other way around
+	hmacToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodeAccessToken(ctx, hmacToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	_, err = s.authManager.DecodeAccessToken(ctx, token)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
+
+// rsaSigner signs with an RSA key held in process, as a service sharing its public key would.
+type rsaSigner struct {
+	privateKey *rsa.PrivateKey
+}
+
+func (s *rsaSigner) Alg() string {
+	return "RS256"
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+}
+
+func (s *rsaSigner) Verify(data []byte, signature []byte) error {
+	digest := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(&s.privateKey.PublicKey, crypto.SHA256, digest[:], signature)
+}
+
+func (s *AuthManagerTestSuite) Test_TokenSigners() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(s.T(), err)
+
+	authManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.ResetPassword},
+		TokenSigners: map[auth_manager.TokenType]auth_manager.Signer{
+			auth_manager.ResetPassword: &rsaSigner{privateKey: privateKey},
+		},
+	})
+
+	accessToken, err := authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	resetToken, err := authManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Both tokens decode with the manager which issued them
+	_, err = authManager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, resetToken, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	// The reset token can be verified by other services with the public key alone
+	_, err = jwt.Parse(resetToken, func(*jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	require.NoError(s.T(), err)
+
+	// Each method is only accepted for its own token type
+	hmacResetManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.ResetPassword},
+	})
+	hmacResetToken, err := hmacResetManager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = authManager.DecodePlainToken(ctx, hmacResetToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	_, err = hmacResetManager.DecodePlainToken(ctx, resetToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
@@ -0,0 +1,51 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// recordAccessToken runs the bookkeeping of a newly issued access token: it becomes the user's current token
+// in SingleSession mode and its issuance is audited.
+func (t *authManager) recordAccessToken(ctx context.Context, uuid string, jti string, expiresAt time.Duration) error {
+	if t.opts.SingleSession {
+		supersededJTI, err := t.redisClient.SetArgs(ctx, t.keys.generateCurrentTokenKey(uuid), jti, redis.SetArgs{
+			TTL: expiresAt,
+			Get: true,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		t.cache.evictUser(uuid, func(value interface{}) bool {
+			claims, ok := value.(AccessTokenClaims)
+			return ok && claims.ID != jti
+		})
+
+		// The tokens before the superseded one were superseded, and published, by earlier logins.
+		if supersededJTI != "" {
+			t.publishInvalidation(ctx, invalidationMessage{JTIs: []string{supersededJTI}})
+		}
+	}
+
+	t.auditAccessToken(ctx, uuid, jti, expiresAt)
+
+	return nil
+}
+
+// isCurrentToken reports whether the jti is the one of the user's current access token.
+// When the current token has expired no token of the user is current anymore.
+func (t *authManager) isCurrentToken(ctx context.Context, uuid string, jti string) (bool, error) {
+	currentJTI, err := t.redisClient.Get(ctx, t.keys.generateCurrentTokenKey(uuid)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return jti != "" && currentJTI == jti, nil
+}
@@ -0,0 +1,55 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_SingleSession() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:    "private-key",
+		SingleSession: true,
+		CacheSize:     10,
+	})
+
+	first, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, first)
+	require.NoError(s.T(), err)
+
+	second, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	// The first token is superseded, even though it was cached
+	_, err = manager.DecodeAccessToken(ctx, first)
+	require.ErrorIs(s.T(), err, auth_manager.ErrSessionSuperseded)
+
+	_, err = manager.DecodeAccessToken(ctx, second)
+	require.NoError(s.T(), err)
+
+	// Other users are not affected
+	other, err := manager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, second)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodeAccessToken(ctx, other)
+	require.NoError(s.T(), err)
+
+	// Token pairs take over the session as well
+	pairAccessToken, _, err := manager.GenerateTokenPair(ctx, userUUID, nil, time.Minute, time.Hour)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, second)
+	require.ErrorIs(s.T(), err, auth_manager.ErrSessionSuperseded)
+	_, err = manager.DecodeAccessToken(ctx, pairAccessToken)
+	require.NoError(s.T(), err)
+}
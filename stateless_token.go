@@ -0,0 +1,166 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type statelessTokenClaims struct {
+	Payload TokenPayload
+	jwt.RegisteredClaims
+}
+
+// stateless reports whether plain tokens of this type are issued as signed jwt instead of being stored in Redis.
+func (t *authManager) stateless(tokenType TokenType) bool {
+	return slices.Contains(t.opts.StatelessTokenTypes, tokenType)
+}
+
+// generateStatelessToken signs the payload into a jwt which is validated by its signature and expiration only,
+// unless TrackStatelessTokens is set.
+func (t *authManager) generateStatelessToken(ctx context.Context, tokenType TokenType, payload *TokenPayload, epoch int64, expiresAt time.Duration) (string, error) {
+	if err := t.validateOpts(); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := statelessTokenClaims{
+		Payload: *payload,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   payload.UUID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresAt)),
+			Issuer:    "go-auth-manager",
+		},
+	}
+	claims.Payload.TokenType = tokenType
+	claims.Payload.Epoch = epoch
+	if !t.opts.SignTokenMeta {
+		claims.Payload.Meta = nil
+	}
+
+	if !t.opts.TrackStatelessTokens {
+		return t.signToken(tokenType, claims)
+	}
+
+	// The jti is the Redis key of the token, so decoding only needs the jwt itself.
+	jti, err := generateRandomString(jtiByteLength)
+	if err != nil {
+		return "", err
+	}
+	claims.ID = jti
+
+	token, err := t.signToken(tokenType, claims)
+	if err != nil {
+		return "", err
+	}
+
+	err = t.redisClient.Set(ctx, t.keys.generateStatelessTokenKey(jti), payload.UUID, expiresAt).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (t *authManager) decodeStatelessToken(ctx context.Context, token string, tokenType TokenType) (*TokenPayload, error) {
+	if !validJWTFormat(token) {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &statelessTokenClaims{}
+	_, err := t.parseWithClaims(token, claims, t.keyFunc)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	if claims.Payload.TokenType != tokenType {
+		return nil, ErrInvalidTokenType
+	}
+	if t.beyondExpiryHorizon(claims.ExpiresAt.Time) {
+		return nil, ErrInvalidToken
+	}
+
+	err = t.checkUserEpoch(ctx, &claims.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.opts.TrackStatelessTokens {
+		if claims.ID == "" {
+			return nil, ErrTokenExpired
+		}
+
+		if t.opts.AcceptOnRedisMiss {
+			revoked, err := t.isRevoked(ctx, claims.ID)
+			if err != nil {
+				return nil, err
+			}
+			if revoked {
+				return nil, ErrTokenRevoked
+			}
+
+			return &claims.Payload, nil
+		}
+
+		count, err := t.redisClient.Exists(ctx, t.keys.generateStatelessTokenKey(claims.ID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrTokenExpired
+		}
+	}
+
+	return &claims.Payload, nil
+}
+
+// destroyStatelessToken deletes the Redis key of a tracked stateless token, found from the jti of the token itself.
+func (t *authManager) destroyStatelessToken(ctx context.Context, token string) (bool, error) {
+	if !validJWTFormat(token) {
+		return false, ErrInvalidToken
+	}
+
+	claims := &statelessTokenClaims{}
+	_, err := t.parseWithClaims(token, claims, t.keyFunc)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return false, nil
+	}
+	if err != nil {
+		return false, parseError(err)
+	}
+	if claims.ID == "" {
+		return false, nil
+	}
+
+	deleted, err := t.redisClient.Del(ctx, t.keys.generateStatelessTokenKey(claims.ID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	// A missing entry no longer invalidates the token, so its jti is revoked for the rest of its lifetime.
+	if t.opts.AcceptOnRedisMiss {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return false, ErrNoExpiration
+		}
+
+		ttl := time.Until(exp.Time)
+		if ttl <= 0 {
+			return deleted > 0, nil
+		}
+
+		revoked, err := t.redisClient.SetNX(ctx, t.keys.generateRevocationKey(claims.ID), 1, ttl).Result()
+		if err != nil {
+			return false, err
+		}
+
+		return deleted > 0 || revoked, nil
+	}
+
+	return deleted > 0, nil
+}
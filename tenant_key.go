@@ -0,0 +1,50 @@
+package auth_manager
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// tenantKeyLength matches the block size of HS512, the longest useful HMAC secret for TokenEncodingAlgorithm.
+const tenantKeyLength = 64
+
+// deriveTenantKey derives the HMAC secret of the tenant from the master key with HKDF-SHA256 (RFC 5869),
+// using the tenant id as the info parameter so every tenant gets an independent secret.
+func deriveTenantKey(masterKey string, tenantID string) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write([]byte(masterKey))
+	pseudoRandomKey := extract.Sum(nil)
+
+	info := []byte("go-auth-manager tenant:" + tenantID)
+
+	key := make([]byte, 0, tenantKeyLength+sha256.Size)
+	var block []byte
+	for counter := byte(1); len(key) < tenantKeyLength; counter++ {
+		expand := hmac.New(sha256.New, pseudoRandomKey)
+		expand.Write(block)
+		expand.Write(info)
+		expand.Write([]byte{counter})
+		block = expand.Sum(nil)
+		key = append(key, block...)
+	}
+
+	return key[:tenantKeyLength]
+}
+
+// secret returns the HMAC secret for the key: the key itself, or the tenant secret derived from it when MasterKey is used.
+func (o AuthManagerOpts) secret(key string) []byte {
+	if o.MasterKey == "" {
+		return []byte(key)
+	}
+
+	return deriveTenantKey(key, o.TenantID)
+}
+
+// signingSecret is the configured secret tokens are signed with, MasterKey when set and PrivateKey otherwise.
+func (o AuthManagerOpts) signingSecret() string {
+	if o.MasterKey != "" {
+		return o.MasterKey
+	}
+
+	return o.PrivateKey
+}
@@ -0,0 +1,61 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_TenantKeys() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	newTenantManager := func(tenantID string) auth_manager.AuthManager {
+		return auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+			MasterKey: "master-key",
+			TenantID:  tenantID,
+		})
+	}
+	tenantA := newTenantManager("tenant-a")
+	tenantB := newTenantManager("tenant-b")
+
+	tokenA, err := tenantA.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	tokenB, err := tenantB.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	// The derived secret is deterministic, another instance of the same tenant accepts the token
+	claims, err := newTenantManager("tenant-a").DecodeAccessToken(ctx, tokenA)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.Payload.UUID)
+
+	_, err = tenantB.DecodeAccessToken(ctx, tokenB)
+	require.NoError(s.T(), err)
+
+	// Tokens don't cross tenants, nor validate under the master key itself
+	_, err = tenantB.DecodeAccessToken(ctx, tokenA)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	_, err = tenantA.DecodeAccessToken(ctx, tokenB)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	masterManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "master-key",
+	})
+	_, err = masterManager.DecodeAccessToken(ctx, tokenA)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// A previous master key keeps deriving the tenant's old secret
+	rotated := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		MasterKey:        "new-master-key",
+		TenantID:         "tenant-a",
+		VerificationKeys: []string{"master-key"},
+	})
+	_, err = rotated.DecodeAccessToken(ctx, tokenA)
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,120 @@
+package auth_manager
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CountByTokenType returns the number of live plain and refresh tokens of each type, e.g. for dashboards.
+// By default the count is exact, which walks every key under KeyPrefix with SCAN and reads each token, so it
+// is as expensive as the keyspace is large. With CountSampleSize the counts are estimated from random keys
+// instead. Access tokens and stateless plain tokens are not stored in Redis and are never counted.
+func (t *authManager) CountByTokenType(ctx context.Context) (map[TokenType]int, error) {
+	if t.opts.CountSampleSize > 0 {
+		return t.sampleByTokenType(ctx, t.opts.CountSampleSize)
+	}
+
+	counts := map[TokenType]int{}
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generatePlainTokenKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.countKeys(ctx, keys, counts)
+		if err != nil {
+			return nil, err
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return counts, nil
+		}
+	}
+}
+
+// sampleByTokenType counts the tokens among sampleSize random keys and scales the counts up to the size of the database.
+func (t *authManager) sampleByTokenType(ctx context.Context, sampleSize int) (map[TokenType]int, error) {
+	size, err := t.redisClient.DBSize(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[TokenType]int{}
+	if size == 0 {
+		return counts, nil
+	}
+
+	keyCmds := make([]*redis.StringCmd, sampleSize)
+	_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range keyCmds {
+			keyCmds[i] = pipe.RandomKey(ctx)
+		}
+		return nil
+	})
+	if err != nil && !isReplyError(err) {
+		return nil, err
+	}
+
+	keys := make([]string, 0, sampleSize)
+	for _, cmd := range keyCmds {
+		if cmd.Err() == nil {
+			keys = append(keys, cmd.Val())
+		}
+	}
+
+	err = t.countKeys(ctx, keys, counts)
+	if err != nil {
+		return nil, err
+	}
+
+	for tokenType, count := range counts {
+		counts[tokenType] = int(int64(count) * size / int64(sampleSize))
+	}
+
+	return counts, nil
+}
+
+// countKeys adds the plain and refresh tokens stored under the keys to the counts, other keys are skipped.
+func (t *authManager) countKeys(ctx context.Context, keys []string, counts map[TokenType]int) error {
+	hashKeyPrefix := t.keys.generateHashKey("")
+
+	getCmds := []*redis.StringCmd{}
+	lenCmds := []*redis.IntCmd{}
+	_, err := t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			if _, ok := t.keys.plainTokenFromKey(key); ok {
+				getCmds = append(getCmds, pipe.Get(ctx, key))
+			} else if strings.HasPrefix(key, hashKeyPrefix) && len(key) > len(hashKeyPrefix) {
+				lenCmds = append(lenCmds, pipe.HLen(ctx, key))
+			}
+		}
+		return nil
+	})
+	if err != nil && !isReplyError(err) {
+		return err
+	}
+
+	// Keys which expired since they were listed or are not tokens fail with redis.Nil or WRONGTYPE.
+	for _, cmd := range getCmds {
+		if cmd.Err() != nil {
+			continue
+		}
+
+		if claims, err := t.decodePayload(cmd.Val()); err == nil {
+			counts[claims.TokenType]++
+		}
+	}
+
+	for _, cmd := range lenCmds {
+		if cmd.Err() == nil && cmd.Val() > 0 {
+			counts[RefreshToken] += int(cmd.Val())
+		}
+	}
+
+	return nil
+}
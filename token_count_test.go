@@ -0,0 +1,72 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_CountByTokenType() {
+	ctx := context.TODO()
+
+	// The prefix keeps the tokens of the other tests out of the count
+	generate := func(manager auth_manager.AuthManager) {
+		for tokenType, count := range map[auth_manager.TokenType]int{auth_manager.VerifyEmail: 3, auth_manager.ResetPassword: 2} {
+			for i := 0; i < count; i++ {
+				_, err := manager.GeneratePlainToken(ctx, tokenType, &auth_manager.TokenPayload{
+					UUID:      uuid.NewString(),
+					CreatedAt: time.Now(),
+				}, time.Minute)
+				require.NoError(s.T(), err)
+			}
+		}
+
+		userUUID := uuid.NewString()
+		for i := 0; i < 4; i++ {
+			_, err := manager.GenerateRefreshToken(ctx, userUUID, &auth_manager.RefreshTokenPayload{}, time.Minute)
+			require.NoError(s.T(), err)
+		}
+
+		// Access tokens are stateless and not counted
+		_, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+		require.NoError(s.T(), err)
+	}
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		KeyPrefix:  uuid.NewString(),
+	})
+	generate(manager)
+
+	counts, err := manager.CountByTokenType(ctx)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), map[auth_manager.TokenType]int{
+		auth_manager.VerifyEmail:   3,
+		auth_manager.ResetPassword: 2,
+		auth_manager.RefreshToken:  4,
+	}, counts)
+
+	// Sampling estimates the counts from random keys of a dedicated database
+	sampleOptions := *redisClient.Options()
+	sampleOptions.DB = 2
+	sampleClient := redis.NewClient(&sampleOptions)
+	require.NoError(s.T(), sampleClient.FlushDB(ctx).Err())
+	defer sampleClient.FlushDB(ctx)
+
+	manager = auth_manager.NewAuthManager(sampleClient, auth_manager.AuthManagerOpts{
+		PrivateKey:      "private-key",
+		CountSampleSize: 2000,
+	})
+	generate(manager)
+
+	counts, err = manager.CountByTokenType(ctx)
+	require.NoError(s.T(), err)
+	require.InDelta(s.T(), 3, counts[auth_manager.VerifyEmail], 1.5)
+	require.InDelta(s.T(), 2, counts[auth_manager.ResetPassword], 1.5)
+	require.InDelta(s.T(), 4, counts[auth_manager.RefreshToken], 2)
+}
@@ -0,0 +1,86 @@
+package auth_manager
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// defaultMaxTokenBytes is the size limit of decoded tokens when AuthManagerOpts.MaxTokenBytes is not set.
+const defaultMaxTokenBytes = 16 * 1024
+
+// maxJWTSegmentLength bounds a single segment, no access token this package signs comes close to it.
+const maxJWTSegmentLength = 8192
+
+var (
+	plainTokenLength   = base64.RawStdEncoding.EncodedLen(TokenByteLength)
+	refreshTokenLength = base64.RawStdEncoding.EncodedLen(refreshTokenByteLength)
+)
+
+// checkTokenSize rejects tokens larger than MaxTokenBytes before any parsing.
+func (t *authManager) checkTokenSize(token string) error {
+	if len(token) > t.maxTokenBytes() {
+		return ErrTokenTooLarge
+	}
+
+	return nil
+}
+
+func (t *authManager) maxTokenBytes() int {
+	if t.opts.MaxTokenBytes > 0 {
+		return t.opts.MaxTokenBytes
+	}
+
+	return defaultMaxTokenBytes
+}
+
+// trimToken drops the whitespace a token picks up when copied from an email or a header. No token contains
+// whitespace, neither the base64 plain tokens used as Redis keys nor jwt, so the trimmed token still matches its key.
+func trimToken(token string) string {
+	return strings.TrimSpace(token)
+}
+
+// validOpaqueFormat is a cheap check that the token could have been produced by generateRandomString,
+// so junk input is rejected before any Redis round-trip.
+func validOpaqueFormat(token string, length int) bool {
+	if len(token) != length {
+		return false
+	}
+
+	for i := 0; i < len(token); i++ {
+		c := token[i]
+		if !isAlphanumeric(c) && c != '+' && c != '/' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validJWTFormat is a cheap check that the token has three base64url segments,
+// so junk input is rejected before any parsing or signature verification.
+func validJWTFormat(token string) bool {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return false
+	}
+
+	for i, segment := range segments {
+		// Only the signature may be empty, which the parser then rejects.
+		if (segment == "" && i < 2) || len(segment) > maxJWTSegmentLength {
+			return false
+		}
+
+		for j := 0; j < len(segment); j++ {
+			c := segment[j]
+			if !isAlphanumeric(c) && c != '-' && c != '_' && c != '=' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
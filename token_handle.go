@@ -0,0 +1,234 @@
+package auth_manager
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GenerateTokenHandle stores a jwt issued by this manager behind an opaque handle, e.g. to send it by email,
+// which ResolveToken turns back into the jwt. The handle lives as long as the jwt.
+func (t *authManager) GenerateTokenHandle(ctx context.Context, jwtToken string) (string, error) {
+	if err := t.checkTokenSize(jwtToken); err != nil {
+		return "", err
+	}
+	if !validJWTFormat(jwtToken) {
+		return "", ErrInvalidToken
+	}
+
+	claims := &statelessTokenClaims{}
+	_, err := t.parseWithClaims(jwtToken, claims, t.keyFunc)
+	if err != nil {
+		return "", parseError(err)
+	}
+
+	handle, err := generateRandomString(TokenByteLength)
+	if err != nil {
+		return "", err
+	}
+
+	handleKey, ttl := t.keys.generateHandleKey(handle), time.Until(claims.ExpiresAt.Time)
+	if t.opts.SeparateHandleSignature {
+		signingString, signature := splitSignature(jwtToken)
+		_, err = t.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, handleKey, handleClaimsField, signingString, handleSignatureField, signature)
+			pipe.PExpire(ctx, handleKey, ttl)
+			return nil
+		})
+	} else {
+		err = t.redisClient.Set(ctx, handleKey, jwtToken, ttl).Err()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return handle, nil
+}
+
+// The fields of a handle stored with SeparateHandleSignature.
+const (
+	handleClaimsField    = "claims"
+	handleSignatureField = "signature"
+)
+
+// resignHandleScript replaces the signature of a handle stored with SeparateHandleSignature, as long as it still
+// holds the claims which were signed. A handle which expired or was rewritten in between is left alone.
+// It returns 1 when the signature was replaced.
+var resignHandleScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'claims') ~= ARGV[1] then
+	return 0
+end
+
+redis.call('HSET', KEYS[1], 'signature', ARGV[2])
+return 1
+`)
+
+// replaceHandleScript replaces the jwt of a handle with the re-signed one, keeping its TTL, as long as it still holds
+// the jwt which was re-signed. A handle which expired or was rewritten in between is left alone. It returns 1 when
+// the jwt was replaced.
+var replaceHandleScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+return 1
+`)
+
+// splitSignature splits a jwt into its signing string, the header and the claims, and its signature.
+func splitSignature(jwtToken string) (string, string) {
+	i := strings.LastIndex(jwtToken, ".")
+	return jwtToken[:i], jwtToken[i+1:]
+}
+
+// handleReads reads a handle in both layouts, only the one it is stored in succeeds.
+type handleReads struct {
+	value  *redis.StringCmd
+	fields *redis.SliceCmd
+}
+
+func readHandle(ctx context.Context, pipe redis.Pipeliner, key string) handleReads {
+	return handleReads{
+		value:  pipe.Get(ctx, key),
+		fields: pipe.HMGet(ctx, key, handleClaimsField, handleSignatureField),
+	}
+}
+
+// jwt returns the jwt stored behind the handle, and whether it was stored with SeparateHandleSignature.
+func (r handleReads) jwt() (string, bool, bool) {
+	if jwtToken, err := r.value.Result(); err == nil {
+		return jwtToken, false, true
+	}
+
+	fields, err := r.fields.Result()
+	if err != nil || len(fields) != 2 {
+		return "", false, false
+	}
+	signingString, ok := fields[0].(string)
+	signature, hasSignature := fields[1].(string)
+	if !ok || !hasSignature {
+		return "", false, false
+	}
+
+	return signingString + "." + signature, true, true
+}
+
+// ResolveToken returns the jwt stored behind the handle by GenerateTokenHandle, or ErrNotFound once it expired.
+// The jwt is returned as is and still has to be decoded by the caller.
+func (t *authManager) ResolveToken(ctx context.Context, handle string) (string, error) {
+	if !validOpaqueFormat(handle, plainTokenLength) {
+		return "", ErrInvalidToken
+	}
+
+	var reads handleReads
+	_, err := t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		reads = readHandle(ctx, pipe, t.keys.generateHandleKey(handle))
+		return nil
+	})
+	if err != nil && !isReplyError(err) {
+		return "", err
+	}
+
+	jwtToken, _, ok := reads.jwt()
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return jwtToken, nil
+}
+
+// MigrateSigningKey re-signs the jwt stored behind handles with newKey, so the handles keep resolving to a
+// verifiable jwt once oldKey is retired. Both keys are master keys when MasterKey is used. The handles are
+// walked in SCAN batches and the ones whose jwt doesn't verify under oldKey are left untouched. Handles stored with
+// SeparateHandleSignature keep their claims byte for byte and only get a new signature.
+// It returns the number of migrated handles, which excludes the handles that expired or were rewritten meanwhile.
+func (t *authManager) MigrateSigningKey(ctx context.Context, oldKey string, newKey string) (int, error) {
+	oldSecret, newSecret := t.opts.secret(oldKey), t.opts.secret(newKey)
+	oldKeyFunc := func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrUnexpectedSigningMethod
+		}
+		return oldSecret, nil
+	}
+
+	migrated := 0
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := t.redisClient.Scan(ctx, cursor, t.keys.generateHandleKey("*"), scanBatchSize).Result()
+		if err != nil {
+			return migrated, err
+		}
+
+		if len(keys) > 0 {
+			reads := make([]handleReads, len(keys))
+			_, err = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, key := range keys {
+					reads[i] = readHandle(ctx, pipe, key)
+				}
+				return nil
+			})
+			if err != nil && !isReplyError(err) {
+				return migrated, err
+			}
+
+			setPipe := t.redisClient.Pipeline()
+			setCmds := make([]*redis.Cmd, 0, len(keys))
+			for i, read := range reads {
+				jwtToken, separate, ok := read.jwt()
+				if !ok {
+					continue
+				}
+
+				claims := jwt.MapClaims{}
+				parsedToken, err := t.parseWithClaims(jwtToken, claims, oldKeyFunc)
+				if err != nil {
+					continue
+				}
+
+				if separate {
+					// The header and the claims are signed as they are stored, so only the signature changes.
+					signingString, _ := splitSignature(jwtToken)
+					signature, err := parsedToken.Method.Sign(signingString, newSecret)
+					if err != nil {
+						return migrated, err
+					}
+
+					setCmds = append(setCmds, resignHandleScript.Eval(ctx, setPipe, []string{keys[i]},
+						signingString, base64.RawURLEncoding.EncodeToString(signature)))
+				} else {
+					resigned, err := jwt.NewWithClaims(TokenEncodingAlgorithm, claims).SignedString(newSecret)
+					if err != nil {
+						return migrated, err
+					}
+
+					setCmds = append(setCmds, replaceHandleScript.Eval(ctx, setPipe, []string{keys[i]}, jwtToken, resigned))
+				}
+			}
+
+			if len(setCmds) > 0 {
+				_, err = setPipe.Exec(ctx)
+				if err != nil && !isReplyError(err) {
+					return migrated, err
+				}
+			}
+
+			for _, cmd := range setCmds {
+				if replaced, err := cmd.Int(); err == nil && replaced == 1 {
+					migrated++
+				}
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,191 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_TokenHandle() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	handle, err := s.authManager.GenerateTokenHandle(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.NotContains(s.T(), handle, ".")
+
+	resolved, err := s.authManager.ResolveToken(ctx, handle)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), accessToken, resolved)
+
+	claims, err := s.authManager.DecodeAccessToken(ctx, resolved)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.Payload.UUID)
+
+	// The handle doesn't outlive the jwt
+	ttl, err := redisClient.TTL(ctx, "token_handle:"+handle).Result()
+	require.NoError(s.T(), err)
+	require.LessOrEqual(s.T(), ttl, time.Minute)
+
+	// Unknown handles
+	unknown, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.ResolveToken(ctx, unknown)
+	require.ErrorIs(s.T(), err, auth_manager.ErrNotFound)
+
+	_, err = s.authManager.ResolveToken(ctx, "junk")
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// Only jwt signed by the manager get a handle
+	foreign := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{PrivateKey: "other-key"})
+	foreignToken, err := foreign.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.GenerateTokenHandle(ctx, foreignToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
+
+func (s *AuthManagerTestSuite) Test_MigrateSigningKey() {
+	ctx := context.TODO()
+	oldManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{PrivateKey: "old-key"})
+	newManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{PrivateKey: "new-key"})
+
+	handles := make([]string, 3)
+	for i := range handles {
+		token, err := oldManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+		require.NoError(s.T(), err)
+
+		handles[i], err = oldManager.GenerateTokenHandle(ctx, token)
+		require.NoError(s.T(), err)
+	}
+
+	migrated, err := newManager.MigrateSigningKey(ctx, "old-key", "new-key")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), len(handles), migrated)
+
+	for _, handle := range handles {
+		token, err := newManager.ResolveToken(ctx, handle)
+		require.NoError(s.T(), err)
+
+		_, err = newManager.DecodeAccessToken(ctx, token)
+		require.NoError(s.T(), err)
+
+		_, err = oldManager.DecodeAccessToken(ctx, token)
+		require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+		ttl, err := redisClient.TTL(ctx, "token_handle:"+handle).Result()
+		require.NoError(s.T(), err)
+		require.Greater(s.T(), ttl, time.Duration(0))
+	}
+
+	// Migrating again finds nothing signed with the old key
+	migrated, err = newManager.MigrateSigningKey(ctx, "old-key", "new-key")
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), migrated)
+}
+
+func (s *AuthManagerTestSuite) Test_MigrateSigningKeyCountsWrites() {
+	ctx := context.TODO()
+	prefix := uuid.NewString()
+	newOpts := func(key string, separate bool) auth_manager.AuthManagerOpts {
+		return auth_manager.AuthManagerOpts{PrivateKey: key, KeyPrefix: prefix, SeparateHandleSignature: separate}
+	}
+
+	generateHandle := func(separate bool) string {
+		oldManager := auth_manager.NewAuthManager(redisClient, newOpts("old-key", separate))
+		token, err := oldManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+		require.NoError(s.T(), err)
+
+		handle, err := oldManager.GenerateTokenHandle(ctx, token)
+		require.NoError(s.T(), err)
+		return prefix + ":token_handle:" + handle
+	}
+	rewrittenKey, expiredKey, migratedKey := generateHandle(false), generateHandle(true), generateHandle(true)
+
+	hook := &afterReadHook{}
+	hookedClient := redis.NewClient(redisClient.Options())
+	hookedClient.AddHook(hook)
+	defer hookedClient.Close()
+
+	// Two of the handles change in between the read of the migration and its writes
+	hook.afterRead = func() {
+		require.NoError(s.T(), redisClient.Set(ctx, rewrittenKey, "rewritten", time.Minute).Err())
+		require.NoError(s.T(), redisClient.Del(ctx, expiredKey).Err())
+	}
+
+	migrated, err := auth_manager.NewAuthManager(hookedClient, newOpts("new-key", false)).
+		MigrateSigningKey(ctx, "old-key", "new-key")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 1, migrated)
+
+	rewritten, err := redisClient.Get(ctx, rewrittenKey).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "rewritten", rewritten)
+
+	exists, err := redisClient.Exists(ctx, expiredKey, migratedKey).Result()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), exists)
+}
+
+func (s *AuthManagerTestSuite) Test_MigrateSigningKeySeparateHandleSignature() {
+	ctx := context.TODO()
+	oldManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:              "old-key",
+		SeparateHandleSignature: true,
+	})
+	newManager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{PrivateKey: "new-key"})
+
+	token, err := oldManager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+
+	handle, err := oldManager.GenerateTokenHandle(ctx, token)
+	require.NoError(s.T(), err)
+
+	// The handle resolves to the jwt, whichever layout the resolving manager writes
+	resolved, err := newManager.ResolveToken(ctx, handle)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), token, resolved)
+
+	handleKey := "token_handle:" + handle
+	claimsBefore, err := redisClient.HGet(ctx, handleKey, "claims").Bytes()
+	require.NoError(s.T(), err)
+	signatureBefore, err := redisClient.HGet(ctx, handleKey, "signature").Result()
+	require.NoError(s.T(), err)
+
+	migrated, err := newManager.MigrateSigningKey(ctx, "old-key", "new-key")
+	require.NoError(s.T(), err)
+	require.GreaterOrEqual(s.T(), migrated, 1)
+
+	// Only the signature was rewritten
+	claimsAfter, err := redisClient.HGet(ctx, handleKey, "claims").Bytes()
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), claimsBefore, claimsAfter)
+	signatureAfter, err := redisClient.HGet(ctx, handleKey, "signature").Result()
+	require.NoError(s.T(), err)
+	require.NotEqual(s.T(), signatureBefore, signatureAfter)
+
+	ttl, err := redisClient.TTL(ctx, handleKey).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, time.Duration(0))
+
+	resolved, err = newManager.ResolveToken(ctx, handle)
+	require.NoError(s.T(), err)
+
+	_, err = newManager.DecodeAccessToken(ctx, resolved)
+	require.NoError(s.T(), err)
+
+	_, err = oldManager.DecodeAccessToken(ctx, resolved)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
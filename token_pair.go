@@ -0,0 +1,64 @@
+package auth_manager
+
+import (
+	"context"
+	"time"
+)
+
+// GenerateTokenPair issues the access and refresh tokens of a login together. The refresh token payload
+// records the jti of the access token it was issued with. If the refresh token can't be stored the access
+// token is revoked again, so either both tokens are valid or neither is. With SingleSession the access token
+// only replaces the current one once the refresh token is stored, so a failed login keeps the previous session.
+func (t *authManager) GenerateTokenPair(ctx context.Context, uuid string, payload *RefreshTokenPayload, accessExpiresAt time.Duration, refreshExpiresAt time.Duration) (string, string, error) {
+	// The access token is checked when it is generated, the refresh token before the access token is issued.
+	if err := t.opts.checkAllowedTokenType(RefreshToken); err != nil {
+		return "", "", err
+	}
+	if err := validateExpiry(refreshExpiresAt); err != nil {
+		return "", "", err
+	}
+
+	uuid, err := t.opts.validateUUID(uuid)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessExpiresAt, err = t.clampExpiry(ctx, uuid, accessExpiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, jti, err := t.generateAccessToken(ctx, uuid, "", accessExpiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshPayload := RefreshTokenPayload{}
+	if payload != nil {
+		refreshPayload = *payload
+	}
+	refreshPayload.AccessTokenID = jti
+
+	refreshToken, err := t.GenerateRefreshToken(ctx, uuid, &refreshPayload, refreshExpiresAt)
+	if err != nil {
+		if revokeErr := t.RevokeByJTI(ctx, jti); revokeErr != nil {
+			return "", "", revokeErr
+		}
+
+		return "", "", err
+	}
+
+	err = t.recordAccessToken(ctx, uuid, jti, accessExpiresAt)
+	if err != nil {
+		if removeErr := t.RemoveRefreshToken(ctx, uuid, refreshToken); removeErr != nil {
+			return "", "", removeErr
+		}
+		if revokeErr := t.RevokeByJTI(ctx, jti); revokeErr != nil {
+			return "", "", revokeErr
+		}
+
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
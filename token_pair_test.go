@@ -0,0 +1,101 @@
+package auth_manager_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// failingHSetHook fails every pipeline which writes a hash, so storing a refresh token fails.
+type failingHSetHook struct {
+	failingPipelineHook
+}
+
+func (failingHSetHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if cmd.Name() == "hset" {
+			return ctx, errors.New("simulated hset failure")
+		}
+	}
+
+	return ctx, nil
+}
+
+func (s *AuthManagerTestSuite) Test_GenerateTokenPair() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	payload := &auth_manager.RefreshTokenPayload{
+		IPAddress: "ip-address",
+		UserAgent: "user-agent",
+	}
+
+	accessToken, refreshToken, err := s.authManager.GenerateTokenPair(ctx, userUUID, payload, time.Minute, time.Hour)
+	require.NoError(s.T(), err)
+
+	accessClaims, err := s.authManager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, accessClaims.Payload.UUID)
+
+	refreshPayload, err := s.authManager.DecodeRefreshToken(ctx, userUUID, refreshToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), payload.IPAddress, refreshPayload.IPAddress)
+	require.Equal(s.T(), accessClaims.ID, refreshPayload.AccessTokenID)
+
+	// The refresh token hash expires with the latest expiring refresh token
+	ttl, err := redisClient.PTTL(ctx, "refresh_token:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, 59*time.Minute)
+
+	_, _, err = s.authManager.GenerateTokenPair(ctx, userUUID, payload, time.Minute, time.Minute)
+	require.NoError(s.T(), err)
+
+	ttl, err = redisClient.PTTL(ctx, "refresh_token:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.Greater(s.T(), ttl, 59*time.Minute)
+}
+
+func (s *AuthManagerTestSuite) Test_GenerateTokenPairRollback() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	failingClient := redis.NewClient(redisClient.Options())
+	failingClient.AddHook(failingHSetHook{})
+	defer failingClient.Close()
+
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:    "private-key",
+		SingleSession: true,
+	}
+	manager := auth_manager.NewAuthManager(redisClient, opts)
+	failingManager := auth_manager.NewAuthManager(failingClient, opts)
+
+	previousToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	revokedBefore, err := redisClient.Keys(ctx, "revoked_jti:*").Result()
+	require.NoError(s.T(), err)
+
+	accessToken, refreshToken, err := failingManager.GenerateTokenPair(ctx, userUUID, nil, time.Minute, time.Hour)
+	require.Error(s.T(), err)
+	require.Empty(s.T(), accessToken)
+	require.Empty(s.T(), refreshToken)
+
+	// No refresh token was stored and the access token was revoked
+	exists, err := redisClient.Exists(ctx, "refresh_token:"+userUUID).Result()
+	require.NoError(s.T(), err)
+	require.Zero(s.T(), exists)
+
+	revokedAfter, err := redisClient.Keys(ctx, "revoked_jti:*").Result()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), revokedAfter, len(revokedBefore)+1)
+
+	// The failed login didn't supersede the session the user already had
+	_, err = manager.DecodeAccessToken(ctx, previousToken)
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,23 @@
+package auth_manager
+
+import (
+	"context"
+	"io"
+)
+
+// DecodeTokenReader decodes a token of the type read from r like DecodeTokenForPurpose does, for streaming
+// sources. At most MaxTokenBytes are read, a longer input is rejected with ErrTokenTooLarge without reading the rest.
+func (t *authManager) DecodeTokenReader(ctx context.Context, r io.Reader, tokenType TokenType) (*TokenPayload, error) {
+	maxTokenBytes := t.maxTokenBytes()
+
+	// One byte past the limit tells an oversized token apart from one of exactly MaxTokenBytes.
+	token, err := io.ReadAll(io.LimitReader(r, int64(maxTokenBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(token) > maxTokenBytes {
+		return nil, t.disclose(ErrTokenTooLarge)
+	}
+
+	return t.decodeTokenOfType(ctx, string(token), tokenType)
+}
@@ -0,0 +1,54 @@
+package auth_manager_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeTokenReader() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	plainToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err := s.authManager.DecodeTokenReader(ctx, bytes.NewReader([]byte(plainToken+"\n")), auth_manager.VerifyEmail)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	claims, err = s.authManager.DecodeTokenReader(ctx, bytes.NewReader([]byte(accessToken)), auth_manager.AccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), userUUID, claims.UUID)
+
+	// The token goes through the same validation as its decode method
+	_, err = s.authManager.DecodeTokenReader(ctx, bytes.NewReader([]byte(plainToken)), auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	// Oversized input is rejected after reading one byte past MaxTokenBytes
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:    "private-key",
+		MaxTokenBytes: 64,
+	})
+
+	reader := bytes.NewReader([]byte(strings.Repeat("a", 1024)))
+	_, err = manager.DecodeTokenReader(ctx, reader, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenTooLarge)
+	require.Equal(s.T(), 1024-65, reader.Len())
+
+	// A token of exactly MaxTokenBytes is not oversized
+	_, err = manager.DecodeTokenReader(ctx, bytes.NewReader([]byte(strings.Repeat("a", 64))), auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+}
@@ -0,0 +1,63 @@
+package auth_manager
+
+import (
+	"context"
+	"time"
+)
+
+// TokenResponse is a generated token ready to be returned as the JSON body of an API response.
+type TokenResponse struct {
+	Token string `json:"token"`
+
+	// ExpiresIn is the lifetime of the token in seconds.
+	ExpiresIn int64 `json:"expiresIn"`
+
+	// ExpiresAt is the expiration of the token formatted as RFC3339.
+	ExpiresAt string `json:"expiresAt"`
+
+	// TokenType is the canonical name of the token type, see TokenType.String.
+	TokenType string `json:"tokenType"`
+}
+
+// GenerateTokenResponse generates an access token for payload.UUID or a plain token of the type, and wraps it in a
+// TokenResponse. Refresh tokens take a RefreshTokenPayload and are not supported.
+func (t *authManager) GenerateTokenResponse(ctx context.Context, tokenType TokenType, payload *TokenPayload, expiresAt time.Duration) (*TokenResponse, error) {
+	now := time.Now()
+
+	// The response reports the lifetime the token is actually given.
+	if payload != nil && tokenType != RefreshToken {
+		uuid, err := t.opts.normalizeUUID(payload.UUID)
+		if err != nil {
+			return nil, err
+		}
+
+		expiresAt, err = t.clampExpiry(ctx, uuid, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var token string
+	var err error
+	switch tokenType {
+	case AccessToken:
+		if payload == nil {
+			return nil, ErrEmptyUUID
+		}
+		token, err = t.GenerateAccessToken(ctx, payload.UUID, expiresAt)
+	case RefreshToken:
+		return nil, ErrUnsupportedTokenType
+	default:
+		token, err = t.GeneratePlainToken(ctx, tokenType, payload, expiresAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:     token,
+		ExpiresIn: int64(expiresAt / time.Second),
+		ExpiresAt: now.Add(expiresAt).UTC().Format(time.RFC3339),
+		TokenType: tokenType.String(),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package auth_manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_GenerateTokenResponse() {
+	ctx := context.TODO()
+	payload := &auth_manager.TokenPayload{
+		UUID:      uuid.NewString(),
+		CreatedAt: time.Now(),
+	}
+
+	before := time.Now().Truncate(time.Second)
+	response, err := s.authManager.GenerateTokenResponse(ctx, auth_manager.AccessToken, payload, time.Minute*15)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.DecodeAccessToken(ctx, response.Token)
+	require.NoError(s.T(), err)
+
+	encoded, err := json.Marshal(response)
+	require.NoError(s.T(), err)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(encoded, &body)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), body, 4)
+	require.Equal(s.T(), response.Token, body["token"])
+	require.Equal(s.T(), float64(900), body["expiresIn"])
+	require.Equal(s.T(), "access_token", body["tokenType"])
+
+	expiresAt, err := time.Parse(time.RFC3339, body["expiresAt"].(string))
+	require.NoError(s.T(), err)
+	require.WithinDuration(s.T(), before.Add(time.Minute*15), expiresAt, time.Second*2)
+
+	// Plain tokens
+	response, err = s.authManager.GenerateTokenResponse(ctx, auth_manager.ResetPassword, payload, time.Hour)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(3600), response.ExpiresIn)
+	require.Equal(s.T(), "reset_password", response.TokenType)
+
+	_, err = s.authManager.DecodePlainToken(ctx, response.Token, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	_, err = s.authManager.GenerateTokenResponse(ctx, auth_manager.RefreshToken, payload, time.Hour)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUnsupportedTokenType)
+}
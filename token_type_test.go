@@ -0,0 +1,32 @@
+package auth_manager_test
+
+import (
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_ParseTokenType() {
+	tokenTypes := map[auth_manager.TokenType]string{
+		auth_manager.ResetPassword: "reset_password",
+		auth_manager.VerifyEmail:   "verify_email",
+		auth_manager.AccessToken:   "access_token",
+		auth_manager.RefreshToken:  "refresh_token",
+		auth_manager.ServiceToken:  "service_token",
+	}
+
+	for tokenType, name := range tokenTypes {
+		require.Equal(s.T(), name, tokenType.String())
+
+		parsed, err := auth_manager.ParseTokenType(tokenType.String())
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), tokenType, parsed)
+	}
+
+	for _, garbage := range []string{"", "RESET_PASSWORD", "reset-password", "TokenType(9)"} {
+		_, err := auth_manager.ParseTokenType(garbage)
+		require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType, garbage)
+	}
+
+	require.Equal(s.T(), "TokenType(9)", auth_manager.TokenType(9).String())
+}
@@ -0,0 +1,72 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tombstone takes the place of a destroyed plain token for SoftDeleteGracePeriod.
+type tombstone struct {
+	Reason    string    `json:"reason"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// writeTombstone records why the plain token was destroyed, when SoftDeleteGracePeriod is set. Like the audit trail
+// it is best effort, the token is already destroyed and a missing tombstone only loses the reason.
+func (t *authManager) writeTombstone(ctx context.Context, token string, reason string) {
+	t.writeTombstones(ctx, []string{token}, reason)
+}
+
+// writeTombstones is writeTombstone for a batch of tokens destroyed for the same reason, in a single pipeline.
+func (t *authManager) writeTombstones(ctx context.Context, tokens []string, reason string) {
+	if t.opts.SoftDeleteGracePeriod <= 0 || len(tokens) == 0 {
+		return
+	}
+
+	encoded, err := t.codec.Marshal(tombstone{Reason: reason, RevokedAt: time.Now()})
+	if err != nil {
+		return
+	}
+
+	_, _ = t.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, token := range tokens {
+			pipe.Set(ctx, t.keys.generateTombstoneKey(token), encoded, t.opts.SoftDeleteGracePeriod)
+		}
+		return nil
+	})
+}
+
+// missingTokenError returns the error of a plain token which is not stored in Redis: ErrTokenRevoked wrapped together
+// with the reason while its tombstone lives, ErrTokenExpired otherwise.
+func (t *authManager) missingTokenError(ctx context.Context, token string) error {
+	if t.opts.SoftDeleteGracePeriod <= 0 {
+		return ErrTokenExpired
+	}
+
+	return t.tombstoneError(t.redisClient.Get(ctx, t.keys.generateTombstoneKey(token)).Result())
+}
+
+// tombstoneError is missingTokenError for a tombstone which was already read.
+func (t *authManager) tombstoneError(encoded string, err error) error {
+	if errors.Is(err, redis.Nil) {
+		return ErrTokenExpired
+	}
+	if err != nil {
+		return err
+	}
+
+	var entry tombstone
+	if err := t.codec.Unmarshal([]byte(encoded), &entry); err != nil {
+		return ErrCorruptedEntry
+	}
+
+	if entry.Reason == "" {
+		return fmt.Errorf("%w at %s", ErrTokenRevoked, entry.RevokedAt.Format(time.RFC3339))
+	}
+
+	return fmt.Errorf("%w at %s: %s", ErrTokenRevoked, entry.RevokedAt.Format(time.RFC3339), entry.Reason)
+}
@@ -0,0 +1,24 @@
+package auth_manager
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// jitterExpiry shortens or lengthens the lifetime by a random fraction of up to TTLJitter, without exceeding
+// MaxExpiryHorizon. The user's validity is applied afterwards by clampExpiry.
+func (t *authManager) jitterExpiry(expiresAt time.Duration) time.Duration {
+	jitter := min(max(t.opts.TTLJitter, 0), 1)
+	if jitter == 0 {
+		return expiresAt
+	}
+
+	spread := float64(expiresAt) * jitter
+	jittered := expiresAt + time.Duration((rand.Float64()*2-1)*spread)
+	if t.opts.MaxExpiryHorizon > 0 {
+		jittered = min(jittered, t.opts.MaxExpiryHorizon)
+	}
+
+	// A jitter of a whole lifetime could leave nothing of it.
+	return max(jittered, time.Millisecond)
+}
@@ -0,0 +1,66 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_TTLJitter() {
+	ctx := context.TODO()
+
+	ttls := func(manager auth_manager.AuthManager, userUUID string) []time.Duration {
+		ttls := make([]time.Duration, 20)
+		for i := range ttls {
+			token, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+				UUID:      userUUID,
+				CreatedAt: time.Now(),
+			}, 100*time.Second)
+			require.NoError(s.T(), err)
+
+			ttls[i], err = redisClient.PTTL(ctx, token).Result()
+			require.NoError(s.T(), err)
+		}
+		return ttls
+	}
+
+	// Without jitter every token gets the requested lifetime
+	for _, ttl := range ttls(s.authManager, uuid.NewString()) {
+		require.InDelta(s.T(), 100*time.Second, ttl, float64(time.Second))
+	}
+
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey: "private-key",
+		TTLJitter:  0.2,
+	})
+
+	jittered := ttls(manager, uuid.NewString())
+	for _, ttl := range jittered {
+		require.GreaterOrEqual(s.T(), ttl, 79*time.Second)
+		require.LessOrEqual(s.T(), ttl, 120*time.Second)
+	}
+	require.NotEqual(s.T(), jittered[0], jittered[1])
+
+	// The jitter never lengthens a lifetime beyond the caps
+	manager = auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:       "private-key",
+		TTLJitter:        0.2,
+		MaxExpiryHorizon: 105 * time.Second,
+		UserValidity:     true,
+	})
+
+	for _, ttl := range ttls(manager, uuid.NewString()) {
+		require.LessOrEqual(s.T(), ttl, 105*time.Second)
+	}
+
+	validUser := uuid.NewString()
+	require.NoError(s.T(), manager.SetUserValidUntil(ctx, validUser, time.Now().Add(90*time.Second)))
+
+	for _, ttl := range ttls(manager, validUser) {
+		require.LessOrEqual(s.T(), ttl, 90*time.Second)
+	}
+}
@@ -0,0 +1,110 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// userEpoch returns the current epoch of the user, zero until the first BumpUserEpoch or when UserEpochs is off.
+func (t *authManager) userEpoch(ctx context.Context, uuid string) (int64, error) {
+	if !t.opts.UserEpochs {
+		return 0, nil
+	}
+
+	epoch, err := t.redisClient.Get(ctx, t.keys.generateEpochKey(uuid)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return epoch, err
+}
+
+// checkUserEpoch rejects the claims of a plain token issued for an older epoch of its user.
+func (t *authManager) checkUserEpoch(ctx context.Context, claims *TokenPayload) error {
+	epoch, err := t.userEpoch(ctx, claims.UUID)
+	if err != nil {
+		return err
+	}
+	if epoch != claims.Epoch {
+		return ErrEpochMismatch
+	}
+
+	return nil
+}
+
+// BumpUserEpoch moves the user to a new epoch, so every token issued for the user so far is rejected
+// with ErrEpochMismatch when UserEpochs is set, without enumerating them.
+func (t *authManager) BumpUserEpoch(ctx context.Context, uuid string) error {
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	err = t.redisClient.Incr(ctx, t.keys.generateEpochKey(uuid)).Err()
+	if err != nil {
+		return err
+	}
+
+	t.evictUser(uuid)
+	t.publishInvalidation(ctx, invalidationMessage{UUIDs: []string{uuid}})
+
+	return nil
+}
+
+// evictUser removes the cached tokens of the user.
+func (t *authManager) evictUser(uuid string) {
+	t.cache.evictUser(uuid, nil)
+}
+
+// forceLogoutReason is the reason recorded in the tombstones of the plain tokens destroyed by ForceLogout.
+const forceLogoutReason = "forced logout"
+
+// ForceLogout logs the user out everywhere without knowing their tokens: it bumps the epoch of the user, so
+// access tokens and stateless tokens are rejected, and destroys the plain tokens in the index of the user along
+// with their refresh tokens. The plain tokens are revoked like DestroyPlainTokenWithReason does. Tokens of other
+// users are left untouched. Without UserEpochs the access tokens would stay valid, so ErrUserEpochsDisabled is
+// returned and nothing is destroyed.
+func (t *authManager) ForceLogout(ctx context.Context, uuid string) error {
+	if !t.opts.UserEpochs {
+		return ErrUserEpochsDisabled
+	}
+
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	err = t.BumpUserEpoch(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := t.redisClient.SMembers(ctx, t.keys.generateIndexKey(uuid)).Result()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(tokens); start += scanBatchSize {
+		batch := tokens[start:min(start+scanBatchSize, len(tokens))]
+
+		keys := make([]string, len(batch))
+		for i, token := range batch {
+			keys[i] = t.keys.generatePlainTokenKey(token)
+		}
+
+		_, err = t.destroyKeys(ctx, keys, forceLogoutReason)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Entries of tokens which already expired are left over by destroyKeys.
+	err = t.redisClient.Del(ctx, t.keys.generateIndexKey(uuid)).Err()
+	if err != nil {
+		return err
+	}
+
+	return t.TerminateRefreshTokens(ctx, uuid)
+}
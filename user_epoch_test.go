@@ -0,0 +1,154 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_UserEpochs() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:          "private-key",
+		UserEpochs:          true,
+		StatelessTokenTypes: []auth_manager.TokenType{auth_manager.VerifyEmail},
+	})
+	payload := &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}
+
+	oldAccessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	oldPlainToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+	oldStatelessToken, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, payload, time.Minute)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, oldAccessToken)
+	require.NoError(s.T(), err)
+
+	err = manager.BumpUserEpoch(ctx, userUUID)
+	require.NoError(s.T(), err)
+
+	// Every token of the older epoch is rejected
+	_, err = manager.DecodeAccessToken(ctx, oldAccessToken)
+	require.ErrorIs(s.T(), err, auth_manager.ErrEpochMismatch)
+	_, err = manager.DecodePlainToken(ctx, oldPlainToken, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrEpochMismatch)
+	_, err = manager.DecodePlainToken(ctx, oldStatelessToken, auth_manager.VerifyEmail)
+	require.ErrorIs(s.T(), err, auth_manager.ErrEpochMismatch)
+
+	// New tokens carry the new epoch
+	newAccessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+	claims, err := manager.DecodeAccessToken(ctx, newAccessToken)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), claims.Payload.Epoch)
+
+	newPlainToken, err := manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, payload, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodePlainToken(ctx, newPlainToken, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+
+	// Other users keep their tokens
+	otherToken, err := manager.GenerateAccessToken(ctx, uuid.NewString(), time.Minute)
+	require.NoError(s.T(), err)
+	err = manager.BumpUserEpoch(ctx, userUUID)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodeAccessToken(ctx, otherToken)
+	require.NoError(s.T(), err)
+}
+
+func (s *AuthManagerTestSuite) Test_ForceLogout() {
+	ctx := context.TODO()
+	opts := auth_manager.AuthManagerOpts{
+		PrivateKey:            "private-key",
+		UserEpochs:            true,
+		CacheSize:             16,
+		SoftDeleteGracePeriod: time.Minute,
+		AuditLogLength:        10,
+	}
+	manager := auth_manager.NewAuthManager(redisClient, opts)
+
+	type userTokens struct {
+		uuid, access, plain, refresh string
+	}
+	generateTokens := func() userTokens {
+		tokens := userTokens{uuid: uuid.NewString()}
+
+		var err error
+		tokens.access, err = manager.GenerateAccessToken(ctx, tokens.uuid, time.Minute)
+		require.NoError(s.T(), err)
+		tokens.plain, err = manager.GeneratePlainToken(ctx, auth_manager.ResetPassword, &auth_manager.TokenPayload{
+			UUID:      tokens.uuid,
+			CreatedAt: time.Now(),
+		}, time.Minute)
+		require.NoError(s.T(), err)
+		tokens.refresh, err = manager.GenerateRefreshToken(ctx, tokens.uuid, &auth_manager.RefreshTokenPayload{}, time.Minute)
+		require.NoError(s.T(), err)
+
+		// Cache the decodes
+		_, err = manager.DecodeAccessToken(ctx, tokens.access)
+		require.NoError(s.T(), err)
+		_, err = manager.DecodePlainToken(ctx, tokens.plain, auth_manager.ResetPassword)
+		require.NoError(s.T(), err)
+
+		return tokens
+	}
+	target := generateTokens()
+	other := generateTokens()
+
+	err := manager.ForceLogout(ctx, target.uuid)
+	require.NoError(s.T(), err)
+
+	_, err = manager.DecodeAccessToken(ctx, target.access)
+	require.ErrorIs(s.T(), err, auth_manager.ErrEpochMismatch)
+	_, err = manager.DecodePlainToken(ctx, target.plain, auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrTokenRevoked)
+	require.ErrorContains(s.T(), err, "forced logout")
+	_, err = manager.DecodeRefreshToken(ctx, target.uuid, target.refresh)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidToken)
+
+	// The plain and refresh tokens are audited as revoked
+	entries, err := manager.AuditLog(ctx, target.uuid)
+	require.NoError(s.T(), err)
+	revokedTypes := []auth_manager.TokenType{}
+	for _, entry := range entries {
+		if entry.Event == auth_manager.AuditRevoked {
+			revokedTypes = append(revokedTypes, entry.TokenType)
+		}
+	}
+	require.ElementsMatch(s.T(), []auth_manager.TokenType{auth_manager.ResetPassword, auth_manager.RefreshToken}, revokedTypes)
+
+	activeTokens, err := manager.ListActiveTokens(ctx, target.uuid)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), activeTokens)
+
+	// The other user is untouched
+	_, err = manager.DecodeAccessToken(ctx, other.access)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodePlainToken(ctx, other.plain, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodeRefreshToken(ctx, other.uuid, other.refresh)
+	require.NoError(s.T(), err)
+
+	// The user logs in again
+	accessToken, err := manager.GenerateAccessToken(ctx, target.uuid, time.Minute)
+	require.NoError(s.T(), err)
+	_, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+
+	// Without UserEpochs the access tokens would survive, nothing is destroyed
+	opts.UserEpochs = false
+	noEpochsManager := auth_manager.NewAuthManager(redisClient, opts)
+	err = noEpochsManager.ForceLogout(ctx, other.uuid)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUserEpochsDisabled)
+	_, err = noEpochsManager.DecodePlainToken(ctx, other.plain, auth_manager.ResetPassword)
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,48 @@
+package auth_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SetUserValidUntil bounds the access of the user, e.g. a contractor, to the given time: with UserValidity set,
+// tokens generated for the user afterwards never outlive it. Tokens generated before are left as they are.
+// The zero time lifts the bound.
+func (t *authManager) SetUserValidUntil(ctx context.Context, uuid string, validUntil time.Time) error {
+	uuid, err := t.opts.normalizeUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	if validUntil.IsZero() {
+		return t.redisClient.Del(ctx, t.keys.generateValidUntilKey(uuid)).Err()
+	}
+
+	return t.redisClient.Set(ctx, t.keys.generateValidUntilKey(uuid), validUntil.UnixMilli(), 0).Err()
+}
+
+// clampExpiry shortens the lifetime of a token generated for the user so it ends with the user's validity, if any.
+// A user whose validity already ended gets ErrUserExpired.
+func (t *authManager) clampExpiry(ctx context.Context, uuid string, expiresAt time.Duration) (time.Duration, error) {
+	if !t.opts.UserValidity {
+		return expiresAt, nil
+	}
+
+	validUntil, err := t.redisClient.Get(ctx, t.keys.generateValidUntilKey(uuid)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return expiresAt, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := time.Until(time.UnixMilli(validUntil))
+	if remaining <= 0 {
+		return 0, ErrUserExpired
+	}
+
+	return min(expiresAt, remaining), nil
+}
@@ -0,0 +1,74 @@
+package auth_manager_test
+
+import (
+	"context"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_UserValidity() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+	manager := auth_manager.NewAuthManager(redisClient, auth_manager.AuthManagerOpts{
+		PrivateKey:   "private-key",
+		UserValidity: true,
+	})
+
+	validUntil := time.Now().Add(time.Minute)
+	err := manager.SetUserValidUntil(ctx, userUUID, validUntil)
+	require.NoError(s.T(), err)
+
+	// A longer lifetime is clamped to the validity of the user
+	accessToken, err := manager.GenerateAccessToken(ctx, userUUID, time.Hour)
+	require.NoError(s.T(), err)
+
+	claims, err := manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.WithinDuration(s.T(), validUntil, claims.ExpiresAt, time.Second)
+
+	plainToken, err := manager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Hour)
+	require.NoError(s.T(), err)
+
+	ttl, err := redisClient.PTTL(ctx, plainToken).Result()
+	require.NoError(s.T(), err)
+	require.LessOrEqual(s.T(), ttl, time.Minute)
+
+	// A shorter lifetime is left unchanged
+	accessToken, err = manager.GenerateAccessToken(ctx, userUUID, time.Second*10)
+	require.NoError(s.T(), err)
+
+	claims, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.WithinDuration(s.T(), time.Now().Add(time.Second*10), claims.ExpiresAt, time.Second)
+
+	// Other users are not bound
+	accessToken, err = manager.GenerateAccessToken(ctx, uuid.NewString(), time.Hour)
+	require.NoError(s.T(), err)
+
+	claims, err = manager.DecodeAccessToken(ctx, accessToken)
+	require.NoError(s.T(), err)
+	require.WithinDuration(s.T(), time.Now().Add(time.Hour), claims.ExpiresAt, time.Second)
+
+	// Nothing is issued once the validity ended, until it is lifted
+	err = manager.SetUserValidUntil(ctx, userUUID, time.Now().Add(-time.Second))
+	require.NoError(s.T(), err)
+
+	_, err = manager.GenerateAccessToken(ctx, userUUID, time.Hour)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUserExpired)
+
+	_, err = manager.CreateSession(ctx, userUUID, time.Hour)
+	require.ErrorIs(s.T(), err, auth_manager.ErrUserExpired)
+
+	err = manager.SetUserValidUntil(ctx, userUUID, time.Time{})
+	require.NoError(s.T(), err)
+
+	_, err = manager.GenerateAccessToken(ctx, userUUID, time.Hour)
+	require.NoError(s.T(), err)
+}
@@ -0,0 +1,57 @@
+package auth_manager_test
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	auth_manager "github.com/tahadostifam/go-auth-manager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *AuthManagerTestSuite) Test_DecodeWrappedToken() {
+	ctx := context.TODO()
+	userUUID := uuid.NewString()
+
+	plainToken, err := s.authManager.GeneratePlainToken(ctx, auth_manager.VerifyEmail, &auth_manager.TokenPayload{
+		UUID:      userUUID,
+		CreatedAt: time.Now(),
+	}, time.Minute)
+	require.NoError(s.T(), err)
+
+	// Unwrapped, wrapped with and without padding
+	for _, token := range []string{
+		plainToken,
+		base64.RawURLEncoding.EncodeToString([]byte(plainToken)),
+		base64.URLEncoding.EncodeToString([]byte(plainToken)),
+	} {
+		claims, err := s.authManager.DecodeWrappedToken(ctx, token, auth_manager.VerifyEmail)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), userUUID, claims.UUID)
+	}
+
+	accessToken, err := s.authManager.GenerateAccessToken(ctx, userUUID, time.Minute)
+	require.NoError(s.T(), err)
+
+	for _, token := range []string{accessToken, base64.RawURLEncoding.EncodeToString([]byte(accessToken))} {
+		claims, err := s.authManager.DecodeWrappedToken(ctx, token, auth_manager.AccessToken)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), userUUID, claims.UUID)
+	}
+
+	// The wrapper doesn't hide the type of the token
+	_, err = s.authManager.DecodeWrappedToken(ctx, base64.RawURLEncoding.EncodeToString([]byte(plainToken)), auth_manager.ResetPassword)
+	require.ErrorIs(s.T(), err, auth_manager.ErrInvalidTokenType)
+
+	// Neither a token nor a wrapped one
+	for _, token := range []string{
+		"not-a-token",
+		base64.RawURLEncoding.EncodeToString([]byte("not-a-token")),
+		base64.RawURLEncoding.EncodeToString([]byte(accessToken)),
+	} {
+		_, err = s.authManager.DecodeWrappedToken(ctx, token, auth_manager.VerifyEmail)
+		require.ErrorIs(s.T(), err, auth_manager.ErrInvalidWrappedToken)
+	}
+}